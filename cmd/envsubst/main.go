@@ -0,0 +1,141 @@
+// Command envsubst substitutes environment variables like GNU envsubst,
+// but it's based on goutils.Expand, which supports bash like parameter expansions
+// and checks syntax strictly.
+//
+// Usage:
+//     envsubst [flags] [SHELL-FORMAT] < input > output
+//
+// If SHELL-FORMAT is given, only variables referenced in it are substituted,
+// e.g. envsubst '$HOME ${USER}'. Expressions reference other variables are output as is.
+// If both -allow and SHELL-FORMAT are given, only variables in both are substituted.
+//
+// Exit codes:
+//     0  success
+//     1  syntax errors in input or SHELL-FORMAT
+//     2  bad usage
+//     3  other errors, e.g. unset variables in strict mode or I/O errors.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hanke0/goutils"
+)
+
+const (
+	exitOK = iota
+	exitSyntax
+	exitUsage
+	exitError
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.LookupEnv))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, lookup func(string) (string, bool)) int {
+	fs := flag.NewFlagSet("envsubst", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		allow     = fs.String("allow", "", "comma separated variable names allowed to substitute, intersected with SHELL-FORMAT")
+		strict    = fs.Bool("strict", false, "fail on unset variables")
+		noBare    = fs.Bool("no-bare", false, "do not substitute $VAR, only ${VAR}")
+		escape    = fs.Bool("escape", false, "output $$ and \\$ as a literal $")
+		variables = fs.Bool("variables", false, "print variables referenced in SHELL-FORMAT and exit")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: envsubst [flags] [SHELL-FORMAT] < input > output")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 1 || (*variables && fs.NArg() == 0) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	opts := []goutils.ExpandOption{goutils.ExpandAllErrors()}
+	if !*noBare {
+		opts = append(opts, goutils.ExpandBareVars())
+	}
+	if *escape {
+		opts = append(opts, goutils.ExpandEscapes())
+	}
+	if *strict {
+		opts = append(opts, goutils.ExpandStrict())
+	}
+
+	var names []string
+	if *allow != "" {
+		names = strings.Split(*allow, ",")
+	}
+	if fs.NArg() == 1 {
+		t, err := goutils.Compile(fs.Arg(0), opts...)
+		if err != nil {
+			return report(stderr, err)
+		}
+		referenced := []string{}
+		seen := map[string]bool{}
+		for _, v := range t.Variables() {
+			if !seen[v.Name] {
+				seen[v.Name] = true
+				referenced = append(referenced, v.Name)
+			}
+		}
+		if *variables {
+			for _, name := range referenced {
+				fmt.Fprintln(stdout, name)
+			}
+			return exitOK
+		}
+		names = intersect(referenced, names)
+	}
+	if names != nil {
+		opts = append(opts, goutils.ExpandOnly(names...))
+	}
+	return report(stderr, goutils.ExpandReaderLookup(stdout, stdin, lookup, opts...))
+}
+
+// intersect returns names in both a and b, b is ignored if it's nil.
+func intersect(a, b []string) []string {
+	if b == nil {
+		return a
+	}
+	names := []string{}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				names = append(names, x)
+				break
+			}
+		}
+	}
+	return names
+}
+
+func report(stderr io.Writer, err error) int {
+	if err == nil {
+		return exitOK
+	}
+	errs, ok := err.(goutils.ExpandErrors)
+	if !ok {
+		errs = goutils.ExpandErrors{err}
+	}
+	for _, e := range errs {
+		fmt.Fprintln(stderr, "envsubst:", e)
+	}
+	var se *goutils.SyntaxError
+	if errors.As(err, &se) {
+		return exitSyntax
+	}
+	return exitError
+}
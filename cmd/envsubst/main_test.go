@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	env := map[string]string{"HOME": "/root", "USER": "root", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	cases := []struct {
+		args   []string
+		input  string
+		want   string
+		stderr string
+		code   int
+	}{
+		{nil, "$HOME ${USER} ${EMPTY-x} ${NONE:-x} $NONE.", "/root root  x .", "", exitOK},
+		{[]string{"-no-bare"}, "$HOME ${USER}", "$HOME root", "", exitOK},
+		{[]string{"-escape"}, "$$HOME \\${USER}", "$HOME ${USER}", "", exitOK},
+		{[]string{"$HOME"}, "$HOME ${USER}", "/root ${USER}", "", exitOK},
+		{[]string{"no variables"}, "$HOME ${USER}", "$HOME ${USER}", "", exitOK},
+		{[]string{"-allow", "USER"}, "$HOME ${USER} $EMPTY", "$HOME root $EMPTY", "", exitOK},
+		{[]string{"-allow", "USER", "$HOME"}, "$HOME ${USER} $EMPTY", "$HOME ${USER} $EMPTY", "", exitOK},
+		{[]string{"-allow", "USER,EMPTY", "$HOME $USER"}, "$HOME ${USER} $EMPTY", "$HOME root $EMPTY", "", exitOK},
+		{[]string{"-variables", "$HOME ${USER:-x} $HOME"}, "", "HOME\nUSER\n", "", exitOK},
+		{[]string{"-allow", "EMPTY", "-variables", "$HOME"}, "", "HOME\n", "", exitOK},
+		{nil, "${HOME\n${}", "${HOME\n${}",
+			"envsubst: bad syntax at 1:1: unclosed expression: ${\n" +
+				"envsubst: bad syntax at 2:1: missing variable name: ${}\n", exitSyntax},
+		{[]string{"${"}, "", "", "envsubst: bad syntax at 1:1: unclosed expression: ${\n", exitSyntax},
+		{[]string{"-strict"}, "$NONE", "$NONE", "envsubst: NONE: unbound variable\n", exitError},
+		{[]string{"a", "b"}, "", "", "Usage", exitUsage},
+		{[]string{"-variables"}, "", "", "Usage", exitUsage},
+		{[]string{"-bad"}, "", "", "flag provided but not defined", exitUsage},
+	}
+	for _, c := range cases {
+		t.Run(strings.Join(c.args, " "), func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(c.args, strings.NewReader(c.input), &stdout, &stderr, lookup)
+			if code != c.code {
+				t.Errorf("want exit code %d, got %d: %s", c.code, code, stderr.String())
+			}
+			if stdout.String() != c.want {
+				t.Errorf("\nwant:%s\ngot:%s", c.want, stdout.String())
+			}
+			if !strings.HasPrefix(stderr.String(), c.stderr) && !strings.Contains(stderr.String(), c.stderr) {
+				t.Errorf("\nwant stderr:%s\ngot:%s", c.stderr, stderr.String())
+			}
+		})
+	}
+}
@@ -1,56 +1,319 @@
 package goutils
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
 // Expand replaces ${var} in the template string base on the mapping function.
 //
 // It's like os.Expand but with following differences:
-//   1. strict syntax checks, return the string replaces as many as we can, and an syntax error.
-//   2. compatible with bash like ${var:=default} syntax.
-//   3. $var outputs as is.
-func Expand(s string, mapping func(varName string, default_ string) string) (string, error) {
-	if len(s) == 0 {
-		return s, nil
+//   1. strict syntax checks, return the string replaces as many as we can, and an *SyntaxError.
+//   2. compatible with bash like parameter expansion syntax:
+//        ${var:-default} ${var-default} ${var:=default} ${var=default}
+//        ${var:+alt} ${var+alt} ${var:?message} ${var?message}
+//        ${#var} ${var#prefix} ${var##prefix} ${var%suffix} ${var%%suffix}
+//        ${var/pattern/repl} ${var//pattern/repl} ${var/#pattern/repl} ${var/%pattern/repl}
+//   3. $var outputs as is unless the ExpandBareVars option is given.
+//   4. defaults, alternates, messages and patterns could contain nested ${...},
+//      e.g. ${HOST:=${DEFAULT_HOST}}. A backslash quotes the next character like bash,
+//      it's removed except in patterns, e.g. ${var:-\}} is "}" and ${var//./\/} replaces "." with "/".
+//   5. optional filter pipelines like ${var|upper}, see ExpandPipeline.
+//
+// The default value of ${var:-default}, ${var-default}, ${var:=default} and ${var=default}
+// is passed to mapping, mapping should returns it if var is unset.
+// Mapping can not tell an unset variable from an empty one, so empty values are treated as unset.
+// Use ExpandLookup if the difference matters.
+//
+// ${var:?message} and ${var?message} expands to empty string and reports an *UnsetError.
+// Values returned by mapping are not expanded unless the ExpandRecursive option is given.
+func Expand(s string, mapping func(varName string, default_ string) string, opts ...ExpandOption) (string, error) {
+	return expand(s, mappingLookup(mapping), opts)
+}
+
+// ExpandE likes Expand but mapping could fail, the error is reported as a *LookupError.
+// It stops expanding at the first mapping error and returns the string expanded so far,
+// unless the ExpandAllErrors option is given, in which case all mapping errors and syntax
+// errors are reported and failed variables are treated as unset.
+func ExpandE(s string, mapping func(varName string, default_ string) (string, error),
+	opts ...ExpandOption) (string, error) {
+	return ExpandContext(context.Background(), s, func(_ context.Context, varName, default_ string) (string, error) {
+		return mapping(varName, default_)
+	}, opts...)
+}
+
+// ExpandContext likes ExpandE but passes ctx to mapping. It stops expanding and
+// reports ctx.Err() once ctx is done, even if the ExpandAllErrors option is given.
+func ExpandContext(ctx context.Context, s string,
+	mapping func(ctx context.Context, varName string, default_ string) (string, error),
+	opts ...ExpandOption) (string, error) {
+	return expandContext(ctx, s, contextLookup(ctx, mapping), opts)
+}
+
+// ExpandLookup likes Expand but gets variables by lookup which reports whether
+// the variable is set, e.g. os.LookupEnv.
+// Defaults are never passed to lookup, they are applied with bash semantics.
+func ExpandLookup(s string, lookup func(varName string) (string, bool), opts ...ExpandOption) (string, error) {
+	return expand(s, plainLookup(lookup), opts)
+}
+
+// UnsetError is returned when a ${var:?message} or ${var?message} expands an unset variable.
+type UnsetError struct {
+	Name    string
+	Message string
+}
+
+func (e *UnsetError) Error() string {
+	if e.Message == "" {
+		return e.Name + ": parameter null or not set"
 	}
+	return e.Name + ": " + e.Message
+}
+
+// RecursionError is returned when a recursive expanding finds a variable cycle
+// or exceeds the max depth.
+type RecursionError struct {
+	// Chain is the variables being expanded, the last one causes the error.
+	Chain []string
+	// Cycle is true if the last variable of Chain is already being expanded.
+	Cycle bool
+}
+
+func (e *RecursionError) Error() string {
+	if e.Cycle {
+		return "expand: variable cycle: " + strings.Join(e.Chain, " -> ")
+	}
+	return "expand: max depth exceeded: " + strings.Join(e.Chain, " -> ")
+}
+
+// LookupError is reported by ExpandE if mapping fails.
+type LookupError struct {
+	Name string
+	Err  error
+}
+
+func (e *LookupError) Error() string {
+	return "expand: lookup " + e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the mapping error.
+func (e *LookupError) Unwrap() error {
+	return e.Err
+}
+
+// SyntaxReason tells why a template has a bad syntax.
+type SyntaxReason int
+
+// Syntax error reasons.
+const (
+	// ReasonUnclosed means ${ has no matching }.
+	ReasonUnclosed SyntaxReason = iota + 1
+	// ReasonMissingName means there is no variable name, e.g. ${} or ${:-x}.
+	ReasonMissingName
+	// ReasonBadOperator means an unknown operator follows the name, e.g. ${var:1}.
+	ReasonBadOperator
+	// ReasonInvalidUTF8 means a byte is not valid utf8, the byte is output as is.
+	ReasonInvalidUTF8
+	// ReasonBadFilter means a filter has no name, e.g. ${var|}.
+	ReasonBadFilter
+	// ReasonUnknownFilter means a filter is not registered.
+	ReasonUnknownFilter
+)
+
+var syntaxReasons = [...]string{
+	ReasonUnclosed:      "unclosed expression",
+	ReasonMissingName:   "missing variable name",
+	ReasonBadOperator:   "bad operator",
+	ReasonInvalidUTF8:   "invalid utf8",
+	ReasonBadFilter:     "bad filter",
+	ReasonUnknownFilter: "unknown filter",
+}
+
+func (r SyntaxReason) String() string {
+	if r > 0 && int(r) < len(syntaxReasons) {
+		return syntaxReasons[r]
+	}
+	return "SyntaxReason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// SyntaxError describes a bad syntax of template.
+type SyntaxError struct {
+	Offset   int    // byte offset of the bad expression.
+	Line     int    // line number, starts from 1.
+	Column   int    // column in characters, starts from 1.
+	Fragment string // the bad expression outputs as is.
+	Reason   SyntaxReason
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("bad syntax at %d:%d: %s: %s", e.Line, e.Column, e.Reason, e.Fragment)
+}
+
+// ExpandErrors is a list of errors reported by Expand if the ExpandAllErrors option is given.
+type ExpandErrors []error
+
+func (e ExpandErrors) Error() string {
 	var sb strings.Builder
-	if !utf8.FullRuneInString(s) {
-		return s, fmt.Errorf("invalid utf8 string: %s", s)
-	}
-	var err error
-	i := 0
-	for j := 0; j < len(s); {
-		r, cursor := nextUTF8Character(s, j)
-		size := cursor - j
-		if r == '$' && j+1 < len(s) {
-			sb.WriteString(s[i:j])
-			name, w := getShellName(s[j+1:])
-			if name == "" && w > 0 {
-				// Encountered invalid syntax; eat the characters.
-				if err == nil {
-					err = fmt.Errorf("bad syntax at %d: %s", j, s)
-				}
-				sb.WriteString(s[j : j+w+1])
-			} else if name == "" {
-				// Valid syntax, but $ was not followed by a
-				// name. Leave the dollar character untouched.
-				sb.WriteByte(s[j])
-			} else {
-				sb.WriteString(mapping(parseVar(name)))
-			}
-			j += w
-			i = j + 1
+	for i, err := range e {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the errors.
+func (e ExpandErrors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any error in e matches target.
+func (e ExpandErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error in e that matches target.
+func (e ExpandErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
 		}
-		j += size
 	}
-	if sb.Len() == 0 {
-		return s, err
+	return false
+}
+
+// ExpandOption configures Expand and its variants.
+type ExpandOption interface {
+	apply(*expandOption)
+}
+
+type expandOption struct {
+	depth     int
+	allErrors bool
+	bare      bool
+	escape    bool
+	strict    bool
+	only      map[string]bool
+	pipeline  bool
+	filters   map[string]ExpandFilter
+}
+
+func (o *expandOption) error(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if o.allErrors {
+		return ExpandErrors(errs)
 	}
-	sb.WriteString(s[i:])
-	return sb.String(), err
+	return errs[0]
+}
+
+type expandOptionFunc func(*expandOption)
+
+func (f expandOptionFunc) apply(o *expandOption) {
+	f(o)
+}
+
+// ExpandRecursive re-expands values returned by mapping, at most depth levels.
+// A *RecursionError is reported if a variable refers to itself or
+// the value still contains variables at the max depth.
+func ExpandRecursive(depth int) ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.depth = depth
+	})
+}
+
+// ExpandAllErrors reports all errors instead of the first one, the returned error is an ExpandErrors.
+func ExpandAllErrors() ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.allErrors = true
+	})
+}
+
+// ExpandBareVars expands $var as ${var}, var follows the shell identifier rules,
+// which is an ASCII letter or underscore followed by ASCII letters, digits or underscores.
+func ExpandBareVars() ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.bare = true
+	})
+}
+
+// ExpandEscapes enables $$ and \$ escapes, both of them output a literal $,
+// e.g. $${var} outputs ${var}.
+func ExpandEscapes() ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.escape = true
+	})
+}
+
+// ExpandStrict rejects unset variables referenced without an operator handles it,
+// e.g. ${var}, ${#var} and ${var#prefix}, instead of expanding them to empty strings.
+// Such expressions output as is and an *UnsetError is reported.
+// Note that Expand treats empty values as unset.
+func ExpandStrict() ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.strict = true
+	})
+}
+
+// ExpandOnly expands the given variables only, expressions reference other
+// variables output as is, e.g. ${HOME} keeps ${HOME} if HOME is not given.
+func ExpandOnly(names ...string) ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.only = make(map[string]bool, len(names))
+		for _, name := range names {
+			o.only[name] = true
+		}
+	})
+}
+
+type lookupFunc func(name, default_ string) (string, bool, error)
+
+func mappingLookup(mapping func(varName string, default_ string) string) lookupFunc {
+	return func(name, def string) (string, bool, error) {
+		v := mapping(name, def)
+		return v, v != "", nil
+	}
+}
+
+func plainLookup(lookup func(varName string) (string, bool)) lookupFunc {
+	return func(name, _ string) (string, bool, error) {
+		v, ok := lookup(name)
+		return v, ok, nil
+	}
+}
+
+func contextLookup(ctx context.Context,
+	mapping func(ctx context.Context, varName string, default_ string) (string, error)) lookupFunc {
+	return func(name, def string) (string, bool, error) {
+		v, err := mapping(ctx, name, def)
+		return v, v != "", err
+	}
+}
+
+func expand(s string, lookup lookupFunc, opts []ExpandOption) (string, error) {
+	return expandContext(context.Background(), s, lookup, opts)
+}
+
+func expandContext(ctx context.Context, s string, lookup lookupFunc, opts []ExpandOption) (string, error) {
+	if len(s) == 0 {
+		return s, nil
+	}
+	t, _ := Compile(s, opts...)
+	out, errs := t.execute(ctx, lookup)
+	return out, t.opt.error(append(t.errs, errs...))
 }
 
 func nextUTF8Character(s string, cursor int) (rune, int) {
@@ -58,32 +321,734 @@ func nextUTF8Character(s string, cursor int) (rune, int) {
 	return r, cursor + size
 }
 
-func getShellName(s string) (string, int) {
-	r, cursor := nextUTF8Character(s, 0)
-	if r != '{' {
-		return "", 0
+// node is a part of template, either a literal string or a variable.
+type node struct {
+	lit string
+	v   *varExpr
+}
+
+// varExpr is a parsed ${...} expression.
+type varExpr struct {
+	name string
+	op   string // "" means ${name}, "len" means ${#name}, others are the bash operators.
+	word []node // default, alternate, message or pattern.
+	repl []node // replacement of ${name/pattern/repl}.
+	raw  string // source text of word.
+	src  string // source text of the expression.
+
+	filters []filterCall
+}
+
+func appendLiteral(nodes []node, lit string) []node {
+	if lit == "" {
+		return nodes
 	}
-	// Scan to closing brace
-	for i := cursor; i < len(s); {
-		r, cur := nextUTF8Character(s, i)
-		if r == '}' {
-			if i == 1 {
-				return "", 2 // Bad syntax; eat "${}"
+	if n := len(nodes); n > 0 && nodes[n-1].v == nil {
+		nodes[n-1].lit += lit
+		return nodes
+	}
+	return append(nodes, node{lit: lit})
+}
+
+type parser struct {
+	s    string
+	pos  int
+	opt  *expandOption
+	errs []error
+
+	// more is true if p.s is a part of input and more input follows.
+	more bool
+	// short is set if parse stops because it needs more input.
+	short bool
+	// failAtEnd is set if the last failed parseVar stops at the end of input.
+	failAtEnd bool
+	// unescape is set if backslashes are removed, see parseWord.
+	unescape bool
+	// offset, line number and column of p.s in the whole input.
+	base, line, col int
+}
+
+// parse parses nodes until the end or any byte of stop.
+func (p *parser) parse(stop string) []node {
+	var nodes []node
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if strings.IndexByte(stop, c) >= 0 {
+			break
+		}
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+			if r == utf8.RuneError && size == 1 {
+				p.errs = append(p.errs, p.syntaxError(p.pos, p.s[p.pos:p.pos+1], ReasonInvalidUTF8))
 			}
-			return s[1:i], cur
+			p.pos += size
+			continue
 		}
-		if r == '{' || r == '$' {
+		var next byte
+		if p.pos+1 < len(p.s) {
+			next = p.s[p.pos+1]
+		} else if p.more && stop == "" && (c == '$' || (c == '\\' && p.opt.escape)) {
+			p.short = true
 			break
 		}
+		if c == '\\' && p.unescape && next != 0 {
+			nodes = appendLiteral(nodes, p.s[start:p.pos])
+			p.pos++
+			start = p.pos
+			if next < utf8.RuneSelf {
+				p.pos++
+			}
+			continue
+		}
+		if p.opt.escape && next == '$' && (c == '$' || c == '\\') {
+			nodes = appendLiteral(nodes, p.s[start:p.pos])
+			nodes = appendLiteral(nodes, "$")
+			p.pos += 2
+			start = p.pos
+			continue
+		}
+		if c == '\\' && strings.IndexByte(stop, '/') >= 0 && next != 0 {
+			// backslash escapes '/' in pattern, keeps it for matchPattern.
+			p.pos++
+			if next < utf8.RuneSelf {
+				p.pos++
+			}
+			continue
+		}
+		if c == '$' && p.opt.bare && isShellNameStart(next) {
+			n := 2
+			for p.pos+n < len(p.s) && isShellNameChar(p.s[p.pos+n]) {
+				n++
+			}
+			if p.pos+n == len(p.s) && p.more && stop == "" {
+				p.short = true
+				break
+			}
+			nodes = appendLiteral(nodes, p.s[start:p.pos])
+			v := &varExpr{name: p.s[p.pos+1 : p.pos+n], src: p.s[p.pos : p.pos+n]}
+			nodes = append(nodes, node{v: v})
+			p.pos += n
+			start = p.pos
+			continue
+		}
+		if c != '$' || next != '{' {
+			p.pos++
+			continue
+		}
+		nodes = appendLiteral(nodes, p.s[start:p.pos])
+		start = p.pos
+		if v, reason := p.parseVar(); v != nil {
+			nodes = append(nodes, node{v: v})
+		} else {
+			// Encountered invalid syntax; eat the characters.
+			n := p.badSyntaxWidth()
+			if p.more && stop == "" && (n == 2 || p.failAtEnd) {
+				// The expression may be completed by the following input.
+				p.short = true
+				break
+			}
+			if n == 2 {
+				reason = ReasonUnclosed
+			}
+			p.errs = append(p.errs, p.syntaxError(p.pos, p.s[p.pos:p.pos+n], reason))
+			p.invalidUTF8(p.pos, p.pos+n)
+			nodes = appendLiteral(nodes, p.s[p.pos:p.pos+n])
+			p.pos += n
+		}
+		start = p.pos
+	}
+	return appendLiteral(nodes, p.s[start:p.pos])
+}
+
+// invalidUTF8 reports invalid utf8 bytes in p.s[from:to].
+func (p *parser) invalidUTF8(from, to int) {
+	for i := from; i < to; {
+		r, size := utf8.DecodeRuneInString(p.s[i:to])
+		if r == utf8.RuneError && size == 1 {
+			p.errs = append(p.errs, p.syntaxError(i, p.s[i:i+1], ReasonInvalidUTF8))
+		}
+		i += size
+	}
+}
+
+// syntaxError creates a SyntaxError at offset of p.s.
+func (p *parser) syntaxError(offset int, fragment string, reason SyntaxReason) *SyntaxError {
+	s := p.s[:offset]
+	line, col := p.line, p.col
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		line += strings.Count(s, "\n")
+		col = 0
+		s = s[i+1:]
+	}
+	return &SyntaxError{
+		Offset:   p.base + offset,
+		Line:     line + 1,
+		Column:   col + utf8.RuneCountInString(s) + 1,
+		Fragment: fragment,
+		Reason:   reason,
+	}
+}
+
+// advance drops the first n bytes of p.s, the positions of later syntax
+// errors keep counting from the beginning.
+func (p *parser) advance(n int) {
+	s := p.s[:n]
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.line += strings.Count(s, "\n")
+		p.col = 0
+		s = s[i+1:]
+	}
+	p.col += utf8.RuneCountInString(s)
+	p.base += n
+	p.s = p.s[n:]
+	p.pos -= n
+}
+
+// badSyntaxWidth returns width of the bad expression at p.pos. It's the whole
+// expression if it's closed, otherwise the leading "${".
+func (p *parser) badSyntaxWidth() int {
+	depth := 0
+	for i := p.pos + 2; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '{':
+			if p.s[i-1] == '$' {
+				depth++
+			}
+		case '}':
+			if depth == 0 {
+				return i + 1 - p.pos
+			}
+			depth--
+		}
+	}
+	return 2
+}
+
+// parseVar parses a ${...} expression at p.pos, it returns nil and the reason
+// if it's a bad syntax.
+func (p *parser) parseVar() (*varExpr, SyntaxReason) {
+	start, nerr := p.pos, len(p.errs)
+	p.pos += 2
+	v, reason := p.parseVarBody()
+	if v != nil && p.opt.pipeline {
+		if reason = p.parseFilters(v); reason != 0 {
+			v = nil
+		}
+	}
+	if v != nil && p.pos >= len(p.s) {
+		v, reason = nil, ReasonUnclosed
+	} else if v != nil && p.s[p.pos] != '}' {
+		// Something follows a complete expression, e.g. ${#a:-x}.
+		v, reason = nil, ReasonBadOperator
+	}
+	if v == nil {
+		p.failAtEnd = p.pos >= len(p.s)-1
+		p.pos = start
+		p.errs = p.errs[:nerr]
+		return nil, reason
+	}
+	p.pos++
+	v.src = p.s[start:p.pos]
+	return v, 0
+}
+
+func (p *parser) parseVarBody() (*varExpr, SyntaxReason) {
+	s := p.s[p.pos:]
+	if len(s) > 1 && s[0] == '#' {
+		n := scanName(s[1:])
+		if n == 0 {
+			return nil, ReasonMissingName
+		}
+		p.pos += n + 1
+		return &varExpr{name: s[1 : n+1], op: "len"}, 0
+	}
+	n := scanName(s)
+	if n == 0 {
+		return nil, ReasonMissingName
+	}
+	v := &varExpr{name: s[:n]}
+	p.pos += n
+	rest := s[n:]
+	if rest == "" || rest[0] == '}' || (rest[0] == '|' && p.opt.pipeline) {
+		return v, 0
+	}
+	var oplen int
+	switch rest[0] {
+	case ':':
+		if len(rest) < 2 || strings.IndexByte("-=?+", rest[1]) < 0 {
+			return nil, ReasonBadOperator
+		}
+		oplen = 2
+	case '-', '=', '?', '+':
+		oplen = 1
+	case '#', '%':
+		oplen = 1
+		if len(rest) > 1 && rest[1] == rest[0] {
+			oplen = 2
+		}
+	case '/':
+		oplen = 1
+		if len(rest) > 1 && strings.IndexByte("/#%", rest[1]) >= 0 {
+			oplen = 2
+		}
+	default:
+		return nil, ReasonBadOperator
+	}
+	v.op = rest[:oplen]
+	p.pos += oplen
+	stop := "}"
+	if p.opt.pipeline {
+		stop = "}|"
+	}
+	if v.op[0] != '/' {
+		start := p.pos
+		// Words of # and % are patterns, which keep backslashes for matchPattern.
+		v.word = p.parseWord(stop, v.op[0] != '#' && v.op[0] != '%')
+		v.raw = p.s[start:p.pos]
+		return v, 0
+	}
+	v.word = p.parseWord("/"+stop, false)
+	if p.pos < len(p.s) && p.s[p.pos] == '/' {
+		p.pos++
+		v.repl = p.parseWord(stop, true)
+	}
+	return v, 0
+}
+
+// parseWord parses a word until any byte of stop, a backslash quotes the next
+// character and is removed if unescape is true, e.g. ${v:-\}} is "}".
+func (p *parser) parseWord(stop string, unescape bool) []node {
+	saved := p.unescape
+	p.unescape = unescape
+	nodes := p.parse(stop)
+	p.unescape = saved
+	return nodes
+}
+
+// parseFilters parses |name:arg|name... after the expression.
+func (p *parser) parseFilters(v *varExpr) SyntaxReason {
+	for p.pos < len(p.s) && p.s[p.pos] == '|' {
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.s) && isFilterNameChar(p.s[p.pos]) {
+			p.pos++
+		}
+		name := p.s[start:p.pos]
+		if name == "" {
+			return ReasonBadFilter
+		}
+		var arg string
+		if p.pos < len(p.s) && p.s[p.pos] == ':' {
+			start = p.pos + 1
+			p.pos = start
+			for p.pos < len(p.s) && p.s[p.pos] != '|' && p.s[p.pos] != '}' {
+				p.pos++
+			}
+			arg = p.s[start:p.pos]
+		}
+		f := p.opt.filter(name)
+		if f == nil {
+			return ReasonUnknownFilter
+		}
+		v.filters = append(v.filters, filterCall{name: name, arg: arg, f: f})
+	}
+	return 0
+}
+
+func isShellNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isShellNameChar(c byte) bool {
+	return isShellNameStart(c) || ('0' <= c && c <= '9')
+}
+
+// isNameRune reports whether r could be a part of ${name}, dot is allowed for
+// paths like ${db.host}.
+func isNameRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func scanName(s string) int {
+	for i := 0; i < len(s); {
+		r, cur := nextUTF8Character(s, i)
+		if !isNameRune(r) {
+			return i
+		}
 		i = cur
 	}
-	return "", 1 // Bad syntax; eat "${"
+	return len(s)
+}
+
+type evaluator struct {
+	ctx     context.Context
+	lookup  lookupFunc
+	opt     *expandOption
+	chain   []string // variables being expanded recursively.
+	errs    []error
+	stopped bool // stops evaluating because of a lookup error.
+}
+
+func (e *evaluator) eval(w io.StringWriter, nodes []node) {
+	for _, n := range nodes {
+		if e.stopped {
+			return
+		}
+		if n.v == nil {
+			_, _ = w.WriteString(n.lit)
+		} else {
+			_, _ = w.WriteString(e.evalVar(n.v))
+		}
+	}
+}
+
+// word evaluates nodes into a string, errors are returned instead of recorded,
+// so they are only reported if the word is used.
+func (e *evaluator) word(nodes []node) (string, []error) {
+	if len(nodes) == 0 {
+		return "", nil
+	}
+	if len(nodes) == 1 && nodes[0].v == nil {
+		return nodes[0].lit, nil
+	}
+	saved, stopped := e.errs, e.stopped
+	e.errs = nil
+	var sb strings.Builder
+	e.eval(&sb, nodes)
+	errs := e.errs
+	e.errs, e.stopped = saved, stopped
+	return sb.String(), errs
+}
+
+// fail records errors, and stops evaluating at lookup errors unless all errors wanted.
+func (e *evaluator) fail(errs ...error) {
+	for _, err := range errs {
+		e.errs = append(e.errs, err)
+		if _, ok := err.(*LookupError); ok && !e.opt.allErrors {
+			e.stopped = true
+		}
+	}
 }
 
-func parseVar(va string) (string, string) {
-	a := strings.SplitN(va, ":=", 2)
-	if len(a) < 2 {
-		return va, ""
+// get looks up a variable, and expands its value if recursive expanding is enabled.
+func (e *evaluator) get(name, def string) (string, bool) {
+	if e.stopped {
+		return "", false
+	}
+	if err := e.ctx.Err(); err != nil {
+		e.errs = append(e.errs, err)
+		e.stopped = true
+		return "", false
+	}
+	val, ok, err := e.lookup(name, def)
+	if err != nil {
+		e.fail(&LookupError{Name: name, Err: err})
+		return "", false
+	}
+	if !ok || e.opt.depth <= 0 || strings.IndexByte(val, '$') < 0 {
+		return val, ok
+	}
+	for _, c := range e.chain {
+		if c == name {
+			e.errs = append(e.errs, e.recursionError(name, true))
+			return val, ok
+		}
+	}
+	if len(e.chain) >= e.opt.depth {
+		e.errs = append(e.errs, e.recursionError(name, false))
+		return val, ok
+	}
+	p := parser{s: val, opt: e.opt}
+	nodes := p.parse("")
+	e.errs = append(e.errs, p.errs...)
+	e.chain = append(e.chain, name)
+	var sb strings.Builder
+	e.eval(&sb, nodes)
+	e.chain = e.chain[:len(e.chain)-1]
+	return sb.String(), ok
+}
+
+func (e *evaluator) recursionError(name string, cycle bool) error {
+	chain := make([]string, len(e.chain), len(e.chain)+1)
+	copy(chain, e.chain)
+	return &RecursionError{Chain: append(chain, name), Cycle: cycle}
+}
+
+func (e *evaluator) evalVar(v *varExpr) string {
+	if e.opt.only != nil && !e.opt.only[v.name] {
+		return v.src
+	}
+	val := e.evalOp(v)
+	for _, f := range v.filters {
+		if e.stopped {
+			break
+		}
+		out, err := f.f(val, f.arg)
+		if err != nil {
+			e.errs = append(e.errs, &FilterError{Name: v.name, Filter: f.name, Err: err})
+			break
+		}
+		val = out
+	}
+	return val
+}
+
+func (e *evaluator) evalOp(v *varExpr) string {
+	word, errs := e.word(v.word)
+	useWord := func() string {
+		e.fail(errs...)
+		return word
+	}
+	switch v.op {
+	case "-", ":-", "=", ":=":
+		// There is no way to assign a variable, so ${var=word} acts as ${var-word}.
+		val, ok := e.get(v.name, word)
+		if !ok || (v.op[0] == ':' && val == "") {
+			return useWord()
+		}
+		return val
+	case "+", ":+":
+		val, ok := e.get(v.name, "")
+		if ok && (v.op == "+" || val != "") {
+			return useWord()
+		}
+		return ""
+	case "?", ":?":
+		val, ok := e.get(v.name, "")
+		if e.stopped {
+			return ""
+		}
+		if !ok || (v.op == ":?" && val == "") {
+			e.errs = append(e.errs, &UnsetError{Name: v.name, Message: useWord()})
+			return ""
+		}
+		return val
+	}
+
+	val, ok := e.get(v.name, "")
+	if e.stopped {
+		return ""
+	}
+	if !ok && e.opt.strict {
+		e.errs = append(e.errs, &UnsetError{Name: v.name, Message: "unbound variable"})
+		return v.src
+	}
+	pattern := useWord()
+	switch v.op {
+	case "":
+		return val
+	case "len":
+		return fmt.Sprint(utf8.RuneCountInString(val))
+	case "#":
+		return trimPrefixPattern(val, pattern, false)
+	case "##":
+		return trimPrefixPattern(val, pattern, true)
+	case "%":
+		return trimSuffixPattern(val, pattern, false)
+	case "%%":
+		return trimSuffixPattern(val, pattern, true)
+	default:
+		repl, errs := e.word(v.repl)
+		e.fail(errs...)
+		return replacePattern(val, pattern, repl, v.op)
+	}
+}
+
+// runeBoundaries returns start offsets of every rune in s, and len(s).
+func runeBoundaries(s string) []int {
+	b := make([]int, 0, len(s)+1)
+	for i := range s {
+		b = append(b, i)
+	}
+	return append(b, len(s))
+}
+
+func trimPrefixPattern(s, pattern string, longest bool) string {
+	b := runeBoundaries(s)
+	for k := range b {
+		i := b[k]
+		if longest {
+			i = b[len(b)-1-k]
+		}
+		if matchPattern(pattern, s[:i]) {
+			return s[i:]
+		}
+	}
+	return s
+}
+
+func trimSuffixPattern(s, pattern string, longest bool) string {
+	b := runeBoundaries(s)
+	for k := range b {
+		i := b[len(b)-1-k]
+		if longest {
+			i = b[k]
+		}
+		if matchPattern(pattern, s[i:]) {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// longestMatchAt returns the end of longest non-empty match starts at b[k], or -1.
+func longestMatchAt(s, pattern string, b []int, k int) int {
+	for e := len(b) - 1; e > k; e-- {
+		if matchPattern(pattern, s[b[k]:b[e]]) {
+			return e
+		}
+	}
+	return -1
+}
+
+func replacePattern(s, pattern, repl, op string) string {
+	switch op {
+	case "/#":
+		if pattern == "" {
+			return repl + s
+		}
+		b := runeBoundaries(s)
+		if e := longestMatchAt(s, pattern, b, 0); e > 0 {
+			return repl + s[b[e]:]
+		}
+		return s
+	case "/%":
+		if pattern == "" {
+			return s + repl
+		}
+		b := runeBoundaries(s)
+		for k := 0; k < len(b)-1; k++ {
+			if matchPattern(pattern, s[b[k]:]) {
+				return s[:b[k]] + repl
+			}
+		}
+		return s
+	}
+	if pattern == "" {
+		return s
+	}
+	b := runeBoundaries(s)
+	var sb strings.Builder
+	last := 0
+	for k := 0; k < len(b)-1; {
+		e := longestMatchAt(s, pattern, b, k)
+		if e < 0 {
+			k++
+			continue
+		}
+		sb.WriteString(s[last:b[k]])
+		sb.WriteString(repl)
+		last = b[e]
+		k = e
+		if op == "/" {
+			break
+		}
+	}
+	if last == 0 {
+		return s
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// matchPattern reports whether s matches the shell pattern entirely.
+// It supports '*', '?', '[...]' and backslash escapes.
+func matchPattern(pattern, s string) bool {
+	px, sx := 0, 0
+	nextPx, nextSx := 0, 0
+	for px < len(pattern) || sx < len(s) {
+		if px < len(pattern) {
+			c := pattern[px]
+			switch c {
+			case '*':
+				// Try to match at sx. If that doesn't work out,
+				// restart at sx+1 next.
+				nextPx = px
+				nextSx = len(s) + 1
+				if sx < len(s) {
+					_, nextSx = nextUTF8Character(s, sx)
+				}
+				px++
+				continue
+			case '?':
+				if sx < len(s) {
+					px++
+					_, sx = nextUTF8Character(s, sx)
+					continue
+				}
+			case '[':
+				if sx < len(s) {
+					r, cur := nextUTF8Character(s, sx)
+					if matched, width := matchClass(pattern[px:], r); width > 0 {
+						if matched {
+							px += width
+							sx = cur
+							continue
+						}
+						break
+					}
+				}
+				if sx < len(s) && s[sx] == '[' {
+					px++
+					sx++
+					continue
+				}
+			default:
+				if c == '\\' && px+1 < len(pattern) {
+					px++
+				}
+				pr, pcur := nextUTF8Character(pattern, px)
+				if sx < len(s) {
+					sr, scur := nextUTF8Character(s, sx)
+					if pr == sr {
+						px = pcur
+						sx = scur
+						continue
+					}
+				}
+			}
+		}
+		// Mismatch. Maybe restart.
+		if 0 < nextSx && nextSx <= len(s) {
+			px = nextPx
+			sx = nextSx
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// matchClass matches r against the bracket expression at the start of pattern.
+// It returns the width of the expression, or 0 if it's not a valid expression.
+func matchClass(pattern string, r rune) (matched bool, width int) {
+	i := 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+	for first := true; i < len(pattern); first = false {
+		if pattern[i] == ']' && !first {
+			return matched != negate, i + 1
+		}
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		lo, cur := nextUTF8Character(pattern, i)
+		hi := lo
+		i = cur
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			i++
+			if pattern[i] == '\\' && i+1 < len(pattern) {
+				i++
+			}
+			hi, i = nextUTF8Character(pattern, i)
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
 	}
-	return a[0], a[1]
+	return false, 0
 }
@@ -1,6 +1,9 @@
 package goutils
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -79,7 +82,7 @@ func TestExpand(t *testing.T) {
 			name:      "var with colon",
 			tpl:       "${name-::=我是}007!",
 			mapping:   nil,
-			want:      "我是007!",
+			want:      "::=我是007!",
 			wantError: false,
 		},
 		{
@@ -89,6 +92,90 @@ func TestExpand(t *testing.T) {
 			want:      ":=我是007!",
 			wantError: false,
 		},
+		{
+			name:      "bad operator",
+			tpl:       "${a:1}007!",
+			mapping:   nil,
+			want:      "${a:1}007!",
+			wantError: true,
+		},
+		{
+			name:      "default",
+			tpl:       "${a:-x}${b-y}${c=z}${d:-}",
+			mapping:   map[string]string{"a": "1"},
+			want:      "1yz",
+			wantError: false,
+		},
+		{
+			name:      "alternate",
+			tpl:       "${a:+x}${b+y}",
+			mapping:   map[string]string{"a": "1"},
+			want:      "x",
+			wantError: false,
+		},
+		{
+			name:      "error",
+			tpl:       "${a:?a is required}!",
+			mapping:   nil,
+			want:      "!",
+			wantError: true,
+		},
+		{
+			name:      "length",
+			tpl:       "${#a}",
+			mapping:   map[string]string{"a": "我是007"},
+			want:      "5",
+			wantError: false,
+		},
+		{
+			name:      "remove prefix and suffix",
+			tpl:       "${a#*/} ${a##*/} ${a%/*} ${a%%/*}",
+			mapping:   map[string]string{"a": "x/y/z"},
+			want:      "y/z z x/y x",
+			wantError: false,
+		},
+		{
+			name:      "replace",
+			tpl:       "${a/o/0} ${a//o/0} ${a/#f/F} ${a/%o/O} ${a//[a-f]} ${a/\\//-}",
+			mapping:   map[string]string{"a": "foo/boo"},
+			want:      "f0o/boo f00/b00 Foo/boo foo/boO oo/oo foo-boo",
+			wantError: false,
+		},
+		{
+			name:      "nested default",
+			tpl:       "${HOST:=${DEFAULT_HOST}}:${PORT:-${A:-${B:-80}}}",
+			mapping:   map[string]string{"DEFAULT_HOST": "localhost"},
+			want:      "localhost:80",
+			wantError: false,
+		},
+		{
+			name:      "nested alternate and pattern",
+			tpl:       "${a:+${a}-${b}} ${a/${b}/x}",
+			mapping:   map[string]string{"a": "12", "b": "2"},
+			want:      "12-2 1x",
+			wantError: false,
+		},
+		{
+			name:      "nested unused error",
+			tpl:       "${a:-${b:?}}",
+			mapping:   map[string]string{"a": "1"},
+			want:      "1",
+			wantError: false,
+		},
+		{
+			name:      "nested bad syntax",
+			tpl:       "${a:-${}}!",
+			mapping:   nil,
+			want:      "${}!",
+			wantError: true,
+		},
+		{
+			name:      "value not expanded",
+			tpl:       "${a}",
+			mapping:   map[string]string{"a": "${b}", "b": "1"},
+			want:      "${b}",
+			wantError: false,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -112,3 +199,321 @@ func TestExpand(t *testing.T) {
 		})
 	}
 }
+
+func TestExpandLookup(t *testing.T) {
+	env := map[string]string{"empty": "", "a": "1"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	cases := []struct {
+		tpl  string
+		want string
+	}{
+		{"${empty-x}", ""},
+		{"${empty:-x}", "x"},
+		{"${unset-x}", "x"},
+		{"${empty=x}", ""},
+		{"${empty:=x}", "x"},
+		{"${empty+x}", "x"},
+		{"${empty:+x}", ""},
+		{"${unset+x}", ""},
+		{"${a:+x}", "x"},
+		{"${empty?}", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.tpl, func(t *testing.T) {
+			got, err := ExpandLookup(c.tpl, lookup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("\nwant:%s\ngot:%s", c.want, got)
+			}
+		})
+	}
+
+	_, err := ExpandLookup("${empty:?must set}", lookup)
+	var ue *UnsetError
+	if !errors.As(err, &ue) || ue.Name != "empty" || ue.Message != "must set" {
+		t.Fatalf("want UnsetError, got %v", err)
+	}
+	_, err = ExpandLookup("${unset?}", lookup)
+	if !errors.As(err, &ue) || ue.Name != "unset" || err.Error() != "unset: parameter null or not set" {
+		t.Fatalf("want UnsetError, got %v", err)
+	}
+}
+
+func TestExpandBackslash(t *testing.T) {
+	env := map[string]string{"v": "foo.bar.baz", "u": "", "e": "x"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	// Wants are outputs of bash 5.2, e.g. a=${v//./\/}; echo "$a".
+	cases := []struct {
+		tpl  string
+		want string
+	}{
+		{`${v//./\/}`, "foo/bar/baz"},
+		{`${u:-\}}`, "}"},
+		{`${u:-a\\b}`, `a\b`},
+		{`${u:-\$HOME}`, "$HOME"},
+		{`${u:-\x}`, "x"},
+		{`${e:+\}\}}`, "}}"},
+		{`${v/foo/\}}`, "}.bar.baz"},
+		{`${v/#foo/a\\b}`, `a\b.bar.baz`},
+		{`${n-a\/b}`, "a/b"},
+		{`${u:-${n:-\}}x}`, "}x"},
+		{`${v//o/\\}`, `f\\.bar.baz`},
+		{`${v/%baz/\$\{e\}}`, "foo.bar.${e}"},
+		{`${v%\.baz}`, "foo.bar"},
+		{`${v#foo\.}`, "bar.baz"},
+		{`${v/\./-}`, "foo-bar.baz"},
+		{`${v//[.]/\\\/}`, `foo\/bar\/baz`},
+	}
+	for _, c := range cases {
+		t.Run(c.tpl, func(t *testing.T) {
+			got, err := ExpandLookup(c.tpl, lookup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("\nwant:%s\ngot:%s", c.want, got)
+			}
+		})
+	}
+	_, err := ExpandLookup(`${u:?a\}b}`, lookup)
+	var ue *UnsetError
+	if !errors.As(err, &ue) || ue.Message != "a}b" {
+		t.Fatalf("want UnsetError, got %v", err)
+	}
+}
+
+func TestExpandRecursive(t *testing.T) {
+	env := map[string]string{
+		"a":     "${b}-${c:-3}",
+		"b":     "${c:=2}",
+		"self":  "${self}",
+		"loop1": "x${loop2}",
+		"loop2": "${loop1}",
+	}
+	mapping := func(name, def string) string {
+		if v, ok := env[name]; ok {
+			return v
+		}
+		return def
+	}
+	got, err := Expand("${a}", mapping, ExpandRecursive(10))
+	if err != nil || got != "2-3" {
+		t.Fatal(got, err)
+	}
+
+	_, err = Expand("${a}", mapping, ExpandRecursive(1))
+	var re *RecursionError
+	if !errors.As(err, &re) || re.Cycle || strings.Join(re.Chain, ",") != "a,b" {
+		t.Fatal(err)
+	}
+
+	_, err = Expand("${self}", mapping, ExpandRecursive(10))
+	if !errors.As(err, &re) || !re.Cycle || strings.Join(re.Chain, ",") != "self,self" {
+		t.Fatal(err)
+	}
+
+	_, err = Expand("${loop1}", mapping, ExpandRecursive(10))
+	if !errors.As(err, &re) || !re.Cycle || err.Error() != "expand: variable cycle: loop1 -> loop2 -> loop1" {
+		t.Fatal(err)
+	}
+}
+
+func TestExpandSyntaxError(t *testing.T) {
+	mapping := func(name, def string) string { return def }
+	_, err := Expand("a\n我${a:1}${", mapping)
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatal(err)
+	}
+	want := SyntaxError{Offset: 5, Line: 2, Column: 2, Fragment: "${a:1}", Reason: ReasonBadOperator}
+	if *se != want {
+		t.Fatalf("\nwant:%+v\ngot:%+v", want, *se)
+	}
+	if se.Error() != "bad syntax at 2:2: bad operator: ${a:1}" {
+		t.Fatal(se.Error())
+	}
+
+	got, err := Expand("${}\n${a:?}${b\n${c", mapping, ExpandAllErrors())
+	if got != "${}\n${b\n${c" {
+		t.Fatal(got)
+	}
+	var errs ExpandErrors
+	if !errors.As(err, &errs) || len(errs) != 4 {
+		t.Fatal(err)
+	}
+	wants := []SyntaxError{
+		{Offset: 0, Line: 1, Column: 1, Fragment: "${}", Reason: ReasonMissingName},
+		{Offset: 10, Line: 2, Column: 7, Fragment: "${", Reason: ReasonUnclosed},
+		{Offset: 14, Line: 3, Column: 1, Fragment: "${", Reason: ReasonUnclosed},
+	}
+	for i, want := range wants {
+		if !errors.As(errs[i], &se) || *se != want {
+			t.Errorf("\nwant:%+v\ngot:%+v", want, errs[i])
+		}
+	}
+	var ue *UnsetError
+	if !errors.As(errs[3], &ue) || ue.Name != "a" {
+		t.Fatal(errs[3])
+	}
+	if ReasonInvalidUTF8.String() != "invalid utf8" || SyntaxReason(0).String() != "SyntaxReason(0)" {
+		t.Fatal("bad reason string")
+	}
+
+	for _, c := range []struct {
+		s    string
+		want SyntaxError
+	}{
+		{"x${#a:-x}", SyntaxError{Offset: 1, Line: 1, Column: 2, Fragment: "${#a:-x}", Reason: ReasonBadOperator}},
+		{"x${#a", SyntaxError{Offset: 1, Line: 1, Column: 2, Fragment: "${", Reason: ReasonUnclosed}},
+		{"\xff${a}", SyntaxError{Offset: 0, Line: 1, Column: 1, Fragment: "\xff", Reason: ReasonInvalidUTF8}},
+		{"中\n a\xe4\xb8", SyntaxError{Offset: 6, Line: 2, Column: 3, Fragment: "\xe4", Reason: ReasonInvalidUTF8}},
+	} {
+		_, err = Expand(c.s, mapping)
+		if !errors.As(err, &se) || *se != c.want {
+			t.Errorf("\nwant:%+v\ngot:%+v", c.want, err)
+		}
+	}
+
+	values := func(name, def string) string { return strings.ToUpper(name) + def }
+	got, err = Expand("${a} \xff ${b}", values)
+	if got != "A \xff B" {
+		t.Fatal(got)
+	}
+	want = SyntaxError{Offset: 5, Line: 1, Column: 6, Fragment: "\xff", Reason: ReasonInvalidUTF8}
+	if !errors.As(err, &se) || *se != want {
+		t.Fatalf("\nwant:%+v\ngot:%+v", want, err)
+	}
+	got, err = Expand("\xe4\xb8${a:-\xfe}\n${c\xff", values, ExpandAllErrors())
+	if got != "\xe4\xb8A\xfe\n${c\xff" {
+		t.Fatal(got)
+	}
+	if !errors.As(err, &errs) || len(errs) != 5 {
+		t.Fatal(err)
+	}
+	wants = []SyntaxError{
+		{Offset: 0, Line: 1, Column: 1, Fragment: "\xe4", Reason: ReasonInvalidUTF8},
+		{Offset: 1, Line: 1, Column: 2, Fragment: "\xb8", Reason: ReasonInvalidUTF8},
+		{Offset: 7, Line: 1, Column: 8, Fragment: "\xfe", Reason: ReasonInvalidUTF8},
+		{Offset: 10, Line: 2, Column: 1, Fragment: "${", Reason: ReasonUnclosed},
+		{Offset: 13, Line: 2, Column: 4, Fragment: "\xff", Reason: ReasonInvalidUTF8},
+	}
+	for i, want := range wants {
+		if !errors.As(errs[i], &se) || *se != want {
+			t.Errorf("\nwant:%+v\ngot:%+v", want, errs[i])
+		}
+	}
+}
+
+func TestExpandModes(t *testing.T) {
+	env := map[string]string{"a": "1", "_b2": "2", "empty": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	cases := []struct {
+		tpl     string
+		opts    []ExpandOption
+		want    string
+		wantErr bool
+	}{
+		{"$a$_b2 $1 $", []ExpandOption{ExpandBareVars()}, "12 $1 $", false},
+		{"$a.x ${c:-$a}", []ExpandOption{ExpandBareVars()}, "1.x 1", false},
+		{"$$a $${a} \\${a} \\a", []ExpandOption{ExpandEscapes()}, "$a ${a} ${a} \\a", false},
+		{"$$a $a", []ExpandOption{ExpandEscapes(), ExpandBareVars()}, "$a 1", false},
+		{"$${a}", nil, "$1", false},
+		{"${a}${empty}${c:-x}${c+y}", []ExpandOption{ExpandStrict()}, "1x", false},
+		{"${c}|${#c}|${c%x}|$c", []ExpandOption{ExpandStrict(), ExpandBareVars()}, "${c}|${#c}|${c%x}|$c", true},
+		{"${a} $_b2 ${c:-${a}${_b2}}", []ExpandOption{ExpandOnly("a", "c"), ExpandBareVars()}, "1 $_b2 1${_b2}", false},
+	}
+	for _, c := range cases {
+		t.Run(c.tpl, func(t *testing.T) {
+			got, err := ExpandLookup(c.tpl, lookup, c.opts...)
+			if (err != nil) != c.wantErr {
+				t.Errorf("wantError:%v,got:%v", c.wantErr, err)
+			}
+			if got != c.want {
+				t.Errorf("\nwant:%s\ngot:%s", c.want, got)
+			}
+		})
+	}
+
+	_, err := ExpandLookup("${c}", lookup, ExpandStrict())
+	var ue *UnsetError
+	if !errors.As(err, &ue) || err.Error() != "c: unbound variable" {
+		t.Fatal(err)
+	}
+}
+
+func TestExpandE(t *testing.T) {
+	errSecret := errors.New("secret not found")
+	mapping := func(name, def string) (string, error) {
+		switch name {
+		case "a":
+			return "1", nil
+		case "secret", "token":
+			return "", errSecret
+		}
+		return def, nil
+	}
+
+	got, err := ExpandE("${a}-${secret}-${a}", mapping)
+	var le *LookupError
+	if !errors.As(err, &le) || le.Name != "secret" || !errors.Is(err, errSecret) {
+		t.Fatal(err)
+	}
+	if got != "1-" {
+		t.Fatal(got)
+	}
+
+	got, err = ExpandE("${a:-${token}}${b:-x}", mapping)
+	if err != nil || got != "1x" {
+		t.Fatal(got, err)
+	}
+
+	got, err = ExpandE("${a}-${secret:-x}-${}-${token}", mapping, ExpandAllErrors())
+	if got != "1-x-${}-" {
+		t.Fatal(got)
+	}
+	var errs ExpandErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatal(err)
+	}
+	if err.Error() != "bad syntax at 1:19: missing variable name: ${}; "+
+		"expand: lookup secret: secret not found; expand: lookup token: secret not found" {
+		t.Fatal(err)
+	}
+}
+
+func TestExpandContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var n int
+	mapping := func(ctx context.Context, name, def string) (string, error) {
+		n++
+		if name == "slow" {
+			cancel()
+			<-ctx.Done()
+		}
+		return name, nil
+	}
+	got, err := ExpandContext(ctx, "${a}${slow}${b}${c}", mapping, ExpandAllErrors())
+	if got != "aslow" || n != 2 {
+		t.Fatal(got, n)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+
+	tpl, _ := Compile("${a}")
+	got, err = tpl.ExecuteContext(context.Background(), mapping)
+	if err != nil || got != "a" {
+		t.Fatal(got, err)
+	}
+}
@@ -0,0 +1,164 @@
+package goutils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ExpandFilter transforms an expanded value in pipelines like ${var|name:arg},
+// arg is the text after colon, or empty if no colon.
+type ExpandFilter func(value string, arg string) (string, error)
+
+// FilterError is reported if a filter fails.
+type FilterError struct {
+	Name   string // variable name
+	Filter string // filter name
+	Err    error
+}
+
+func (e *FilterError) Error() string {
+	return "expand: filter " + e.Filter + " of " + e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the filter error.
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+type filterCall struct {
+	name string
+	arg  string
+	f    ExpandFilter
+}
+
+var expandFilters = struct {
+	mu sync.RWMutex
+	m  map[string]ExpandFilter
+}{
+	m: map[string]ExpandFilter{
+		"upper":      stringFilter(strings.ToUpper),
+		"lower":      stringFilter(strings.ToLower),
+		"title":      stringFilter(titleString),
+		"trim":       trimFilter,
+		"trimprefix": argFilter(strings.TrimPrefix),
+		"trimsuffix": argFilter(strings.TrimSuffix),
+		"shell":      stringFilter(shellQuote),
+		"json":       stringFilter(func(s string) string { return ToJSON(s) }),
+		"url":        stringFilter(url.QueryEscape),
+		"base64":     stringFilter(func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }),
+		"base64url":  stringFilter(func(s string) string { return base64.URLEncoding.EncodeToString([]byte(s)) }),
+		"hex":        stringFilter(func(s string) string { return hex.EncodeToString([]byte(s)) }),
+		"default":    defaultFilter,
+		"join":       joinFilter,
+	},
+}
+
+// RegisterExpandFilter registers a filter for all templates, it replaces the
+// filter of same name, including the builtin ones.
+//
+// Builtin filters:
+//     upper, lower, title       change case.
+//     trim[:cutset]             trims spaces, or characters in cutset.
+//     trimprefix:s, trimsuffix:s
+//     shell, json, url          quote as a shell word, a JSON string and a URL query component.
+//     base64, base64url, hex    encode value.
+//     default:s                 replaces empty value with s.
+//     join:sep                  joins space separated fields with sep.
+func RegisterExpandFilter(name string, f ExpandFilter) {
+	expandFilters.mu.Lock()
+	expandFilters.m[name] = f
+	expandFilters.mu.Unlock()
+}
+
+// ExpandPipeline enables filter pipelines like ${var|trim|upper} or ${list|join:,}.
+// The filters are applied in order after expanding the expression.
+// Unknown filters are syntax errors. Note that '|' can not be used in defaults or
+// patterns when pipelines are enabled.
+func ExpandPipeline() ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.pipeline = true
+	})
+}
+
+// ExpandFilters enables filter pipelines with filters, which take precedence
+// over the registered ones.
+func ExpandFilters(filters map[string]ExpandFilter) ExpandOption {
+	return expandOptionFunc(func(o *expandOption) {
+		o.pipeline = true
+		o.filters = filters
+	})
+}
+
+func (o *expandOption) filter(name string) ExpandFilter {
+	if f, ok := o.filters[name]; ok {
+		return f
+	}
+	expandFilters.mu.RLock()
+	f := expandFilters.m[name]
+	expandFilters.mu.RUnlock()
+	return f
+}
+
+func isFilterNameChar(c byte) bool {
+	return isShellNameChar(c) || c == '-'
+}
+
+func stringFilter(f func(string) string) ExpandFilter {
+	return func(value string, _ string) (string, error) {
+		return f(value), nil
+	}
+}
+
+func argFilter(f func(string, string) string) ExpandFilter {
+	return func(value string, arg string) (string, error) {
+		return f(value, arg), nil
+	}
+}
+
+func trimFilter(value string, cutset string) (string, error) {
+	if cutset == "" {
+		return strings.TrimSpace(value), nil
+	}
+	return strings.Trim(value, cutset), nil
+}
+
+func defaultFilter(value string, def string) (string, error) {
+	if value == "" {
+		return def, nil
+	}
+	return value, nil
+}
+
+func joinFilter(value string, sep string) (string, error) {
+	return strings.Join(strings.Fields(value), sep), nil
+}
+
+func titleString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	prev := ' '
+	for _, r := range s {
+		if unicode.IsSpace(prev) {
+			r = unicode.ToTitle(r)
+		}
+		sb.WriteRune(r)
+		prev = r
+	}
+	return sb.String()
+}
+
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for i := 0; i < len(s); i++ {
+		if !isShellNameChar(s[i]) && strings.IndexByte("@%+=:,./-", s[i]) < 0 {
+			return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+		}
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package goutils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestExpandPipeline(t *testing.T) {
+	env := LookupMap(map[string]string{
+		"NAME":  " hello world ",
+		"LIST":  "a b  c",
+		"VALUE": `say "hi" <&>`,
+		"EMPTY": "",
+	})
+	cases := []struct {
+		tpl  string
+		want string
+	}{
+		{"${NAME|upper}", " HELLO WORLD "},
+		{"${NAME|trim|title}", "Hello World"},
+		{"${NAME|trim: hd}", "ello worl"},
+		{"${NAME|trim|trimprefix:hello|trimsuffix:world|lower}", " "},
+		{"${LIST|join:,}", "a,b,c"},
+		{"${VALUE|json}", `"say \"hi\" <&>"`},
+		{"${VALUE|shell} ${LIST|hex|shell} ${EMPTY|shell}", `'say "hi" <&>' 612062202063 ''`},
+		{"${VALUE|url}", "say+%22hi%22+%3C%26%3E"},
+		{"${LIST|base64} ${VALUE|base64url}", "YSBiICBj c2F5ICJoaSIgPCY-"},
+		{"${EMPTY|default:x} ${NONE:-y|upper} ${#LIST|default:0}", "x Y 6"},
+		{"${LIST// /-|upper} ${LIST/#a/${NONE:-x|upper}|upper}", "A-B--C X B  C"},
+	}
+	for _, c := range cases {
+		t.Run(c.tpl, func(t *testing.T) {
+			got, err := ExpandLookup(c.tpl, env, ExpandPipeline())
+			assert.Nil(t, err)
+			assert.Equal(t, got, c.want)
+		})
+	}
+
+	got, err := ExpandLookup("${NAME|upper}", env)
+	assert.NotNil(t, err)
+	assert.Equal(t, got, "${NAME|upper}")
+}
+
+func TestExpandFilters(t *testing.T) {
+	errTooLong := errors.New("too long")
+	filters := map[string]ExpandFilter{
+		"repeat": func(value, arg string) (string, error) {
+			if len(arg) > 1 {
+				return "", errTooLong
+			}
+			return strings.Repeat(value, int(arg[0]-'0')), nil
+		},
+		"upper": stringFilter(strings.ToLower),
+	}
+	RegisterExpandFilter("reverse", stringFilter(func(s string) string {
+		r := []rune(s)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r)
+	}))
+	env := LookupMap(map[string]string{"A": "Ab"})
+	got, err := ExpandLookup("${A|repeat:2|reverse} ${A|upper}", env, ExpandFilters(filters))
+	assert.Nil(t, err)
+	assert.Equal(t, got, "bAbA ab")
+
+	got, err = ExpandLookup("${A|repeat:10}!", env, ExpandFilters(filters))
+	assert.Equal(t, got, "Ab!")
+	var fe *FilterError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, err.Error(), "expand: filter repeat of A: too long")
+	assert.True(t, errors.Is(err, errTooLong))
+
+	got, err = ExpandLookup("${A|none}${A|}${A|upper", env, ExpandPipeline(), ExpandAllErrors())
+	assert.Equal(t, got, "${A|none}${A|}${A|upper")
+	var errs ExpandErrors
+	assert.True(t, errors.As(err, &errs))
+	reasons := []SyntaxReason{ReasonUnknownFilter, ReasonBadFilter, ReasonUnclosed}
+	for i, r := range reasons {
+		var se *SyntaxError
+		assert.True(t, errors.As(errs[i], &se))
+		assert.Equal(t, se.Reason, r)
+	}
+}
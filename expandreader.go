@@ -0,0 +1,103 @@
+package goutils
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"unicode/utf8"
+)
+
+const expandReaderChunkSize = 32 * 1024
+
+// ExpandReader likes Expand but reads the template from src and writes the result to dst.
+//
+// The template is handled chunk by chunk, an expression splits across chunks is
+// hold until it's complete, so the result and error positions are the same as Expand.
+// Note that an unclosed ${ holds the rest of input in memory.
+func ExpandReader(dst io.Writer, src io.Reader, mapping func(varName string, default_ string) string,
+	opts ...ExpandOption) error {
+	return expandReader(dst, src, mappingLookup(mapping), opts)
+}
+
+// ExpandReaderLookup likes ExpandReader but gets variables by lookup, see ExpandLookup for details.
+func ExpandReaderLookup(dst io.Writer, src io.Reader, lookup func(varName string) (string, bool),
+	opts ...ExpandOption) error {
+	return expandReader(dst, src, plainLookup(lookup), opts)
+}
+
+func expandReader(dst io.Writer, src io.Reader, lookup lookupFunc, opts []ExpandOption) error {
+	var o expandOption
+	for _, a := range opts {
+		a.apply(&o)
+	}
+	bw, ok := dst.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(dst)
+	}
+	p := parser{opt: &o, more: true}
+	e := evaluator{ctx: context.Background(), lookup: lookup, opt: &o}
+	var buf []byte
+	want := 1
+	for p.more {
+		var err error
+		buf, err = readAtLeast(src, buf, want)
+		if err == io.EOF {
+			p.more = false
+		} else if err != nil {
+			return err
+		}
+
+		n := len(buf)
+		if p.more {
+			n = completeRunes(buf)
+		}
+		p.s = string(buf[:n])
+		p.pos = 0
+		p.short = false
+		nodes := p.parse("")
+		e.eval(bw, nodes)
+		want = len(buf) - p.pos + 1
+		if p.pos == 0 && len(buf) > 0 {
+			// Reads at least as much as pending bytes, so a long pending expression
+			// is not parsed again and again.
+			want = 2 * len(buf)
+		}
+		buf = buf[:copy(buf, buf[p.pos:])]
+		p.advance(p.pos)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return o.error(append(p.errs, e.errs...))
+}
+
+// readAtLeast reads from r and appends to buf until len(buf) >= n.
+// It returns io.EOF only if r is drained.
+func readAtLeast(r io.Reader, buf []byte, n int) ([]byte, error) {
+	if size := n + expandReaderChunkSize; cap(buf) < size {
+		nb := make([]byte, len(buf), size)
+		copy(nb, buf)
+		buf = nb
+	}
+	for len(buf) < n {
+		m, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+m]
+		if err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// completeRunes returns the length of buf without the trailing incomplete rune.
+func completeRunes(buf []byte) int {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if utf8.FullRune(buf[i:]) {
+				return len(buf)
+			}
+			return i
+		}
+	}
+	return len(buf)
+}
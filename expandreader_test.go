@@ -0,0 +1,64 @@
+package goutils
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestExpandReader(t *testing.T) {
+	env := map[string]string{"a": "1", "b": "${a}", "中": "文"}
+	mapping := func(name, def string) string {
+		if v, ok := env[name]; ok {
+			return v
+		}
+		return def
+	}
+	cases := []struct {
+		tpl  string
+		opts []ExpandOption
+	}{
+		{"", nil},
+		{"no variables 我是007", nil},
+		{"${a}${b}${中}\n${c:-${a:+x}}${a/1/2} $a $", nil},
+		{"${a/\\}/x}${a:}${a:=}${#a}${#}${a", nil},
+		{"${}\n${0${name:=07}!\n${c:?}${", []ExpandOption{ExpandAllErrors()}},
+		{"$a$$b\\$a $_a1 $\n${c}$", []ExpandOption{ExpandBareVars(), ExpandEscapes(), ExpandAllErrors()}},
+		{"a $ab $a", []ExpandOption{ExpandBareVars()}},
+		{"$a ${b} $c", []ExpandOption{ExpandBareVars(), ExpandOnly("a", "b")}},
+		{"${b} ${c} ${中}", []ExpandOption{ExpandRecursive(2), ExpandStrict(), ExpandAllErrors()}},
+		{"${" + strings.Repeat("${a}中", expandReaderChunkSize), []ExpandOption{ExpandAllErrors()}},
+		{strings.Repeat("${a}中$", expandReaderChunkSize), nil},
+		{"ok ${a} \xff", nil},
+		{"中\xe4\xb8${a}\xe4\xb8\xad\n${c:-\xff}\xf0\x9f${a", []ExpandOption{ExpandAllErrors()}},
+		{strings.Repeat("a", expandReaderChunkSize) + "\xe4\xb8x${a}\xff", []ExpandOption{ExpandAllErrors()}},
+		{strings.Repeat("a", expandReaderChunkSize-1) + "\xf0\x9f\x98${a}\n\xf0\x9f\x98\x80", []ExpandOption{ExpandAllErrors()}},
+	}
+	for _, c := range cases {
+		want, wantErr := Expand(c.tpl, mapping, c.opts...)
+		readers := []io.Reader{
+			strings.NewReader(c.tpl),
+			iotest.HalfReader(strings.NewReader(c.tpl)),
+			iotest.OneByteReader(strings.NewReader(c.tpl)),
+			io.MultiReader(strings.NewReader(c.tpl[:len(c.tpl)/2]), strings.NewReader(c.tpl[len(c.tpl)/2:])),
+		}
+		for _, r := range readers {
+			var buf bytes.Buffer
+			err := ExpandReader(&buf, r, mapping, c.opts...)
+			assert.Equal(t, buf.String(), want)
+			assert.Equal(t, err, wantErr)
+		}
+	}
+}
+
+func TestExpandReaderError(t *testing.T) {
+	mapping := func(name, def string) string { return def }
+	err := ExpandReader(&bytes.Buffer{}, iotest.ErrReader(iotest.ErrTimeout), mapping)
+	assert.Equal(t, err, iotest.ErrTimeout)
+	err = ExpandReader(errWriter{}, strings.NewReader("abc"), mapping)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,104 @@
+package goutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ExpandStruct expands every string in the value pointed by v by mapping,
+// see Expand for details.
+//
+// Exported struct fields, map values, slice and array elements, pointers and interfaces
+// are walked recursively. Map keys and unexported fields are kept unchanged.
+// Errors are prefixed with the path of the string, e.g. "DB.Hosts[0]: ...", a top-level *string has no prefix.
+func ExpandStruct(v interface{}, mapping func(varName string, default_ string) string, opts ...ExpandOption) error {
+	return expandStruct(v, mappingLookup(mapping), opts)
+}
+
+// ExpandStructLookup likes ExpandStruct but gets variables by lookup, see ExpandLookup for details.
+func ExpandStructLookup(v interface{}, lookup func(varName string) (string, bool), opts ...ExpandOption) error {
+	return expandStruct(v, plainLookup(lookup), opts)
+}
+
+func expandStruct(v interface{}, lookup lookupFunc, opts []ExpandOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("expand: ExpandStruct requires a non-nil pointer")
+	}
+	w := structExpander{lookup: lookup, opts: opts, visited: map[uintptr]bool{}}
+	for _, a := range opts {
+		a.apply(&w.opt)
+	}
+	w.walk(rv, "")
+	return w.opt.error(w.errs)
+}
+
+type structExpander struct {
+	lookup  lookupFunc
+	opts    []ExpandOption
+	opt     expandOption
+	visited map[uintptr]bool
+	errs    []error
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// walk expands strings in v, v must be settable if it's a string or an interface.
+func (w *structExpander) walk(v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.String:
+		out, err := expand(v.String(), w.lookup, w.opts)
+		if err != nil {
+			if path != "" {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+			w.errs = append(w.errs, err)
+		}
+		v.SetString(out)
+	case reflect.Ptr:
+		if v.IsNil() || w.visited[v.Pointer()] {
+			return
+		}
+		w.visited[v.Pointer()] = true
+		w.walk(v.Elem(), path)
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		e := v.Elem()
+		if e.Kind() == reflect.Ptr {
+			w.walk(e, path)
+			return
+		}
+		c := reflect.New(e.Type()).Elem()
+		c.Set(e)
+		w.walk(c, path)
+		v.Set(c)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" {
+				w.walk(v.Field(i), joinPath(path, f.Name))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			w.walk(v.Index(i), path+"["+strconv.Itoa(i)+"]")
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			c := reflect.New(v.Type().Elem()).Elem()
+			c.Set(iter.Value())
+			w.walk(c, fmt.Sprintf("%s[%v]", path, iter.Key()))
+			v.SetMapIndex(iter.Key(), c)
+		}
+	}
+}
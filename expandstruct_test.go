@@ -0,0 +1,74 @@
+package goutils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+type expandDB struct {
+	Hosts []string
+	Port  string
+}
+
+type expandConfig struct {
+	Name    string
+	DB      *expandDB
+	Labels  map[string]string
+	Extra   map[string]interface{}
+	Nested  [2]expandDB
+	Self    *expandConfig
+	Count   int
+	private string
+}
+
+func TestExpandStruct(t *testing.T) {
+	c := &expandConfig{
+		Name:   "${NAME}",
+		DB:     &expandDB{Hosts: []string{"${HOST}", "${HOST}-2"}, Port: "${PORT:-3306}"},
+		Labels: map[string]string{"${NAME}": "${NAME}"},
+		Extra: map[string]interface{}{
+			"s":    "${NAME}",
+			"list": []interface{}{"${HOST}", 1},
+			"db":   expandDB{Port: "${PORT:-1}"},
+		},
+		Nested:  [2]expandDB{{Port: "${PORT:-1}"}},
+		Count:   1,
+		private: "${NAME}",
+	}
+	c.Self = c
+	env := LookupMap(map[string]string{"NAME": "app", "HOST": "localhost"})
+	err := ExpandStructLookup(c, env)
+	assert.Nil(t, err)
+	assert.Equal(t, c.Name, "app")
+	assert.Equal(t, c.DB, &expandDB{Hosts: []string{"localhost", "localhost-2"}, Port: "3306"})
+	assert.Equal(t, c.Labels, map[string]string{"${NAME}": "app"})
+	assert.Equal(t, c.Extra, map[string]interface{}{
+		"s":    "app",
+		"list": []interface{}{"localhost", 1},
+		"db":   expandDB{Port: "1"},
+	})
+	assert.Equal(t, c.Nested[0].Port, "1")
+	assert.Equal(t, c.private, "${NAME}")
+}
+
+func TestExpandStructError(t *testing.T) {
+	c := expandConfig{Name: "${}", DB: &expandDB{Hosts: []string{"${HOST:?}"}}}
+	err := ExpandStruct(c, LookupEnv().Mapping())
+	assert.NotNil(t, err)
+
+	err = ExpandStruct(&c, func(string, string) string { return "" }, ExpandAllErrors())
+	var errs ExpandErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, len(errs), 2)
+	assert.Equal(t, errs[0].Error(), "Name: bad syntax at 1:1: missing variable name: ${}")
+	assert.Equal(t, errs[1].Error(), "DB.Hosts[0]: HOST: parameter null or not set")
+	var ue *UnsetError
+	assert.True(t, errors.As(err, &ue))
+
+	s := "${HOST:?}"
+	err = ExpandStruct(&s, func(string, string) string { return "" })
+	assert.Equal(t, err.Error(), "HOST: parameter null or not set")
+	assert.True(t, errors.As(err, &ue))
+}
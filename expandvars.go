@@ -0,0 +1,187 @@
+package goutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// VarLookup looks up a variable and reports whether it's set, it's used by ExpandLookup.
+type VarLookup func(varName string) (string, bool)
+
+// Mapping converts l to a mapping function of Expand, which returns
+// the template default if the variable is not found.
+func (l VarLookup) Mapping() func(varName string, default_ string) string {
+	return func(varName string, default_ string) string {
+		if v, ok := l(varName); ok {
+			return v
+		}
+		return default_
+	}
+}
+
+// LookupEnv looks up variables from the process environment.
+func LookupEnv() VarLookup {
+	return os.LookupEnv
+}
+
+// LookupMap looks up variables from m.
+func LookupMap(m map[string]string) VarLookup {
+	return func(varName string) (string, bool) {
+		v, ok := m[varName]
+		return v, ok
+	}
+}
+
+// LookupChain looks up variables from ls in order, the first found value is returned.
+func LookupChain(ls ...VarLookup) VarLookup {
+	return func(varName string) (string, bool) {
+		for _, l := range ls {
+			if v, ok := l(varName); ok {
+				return v, true
+			}
+		}
+		return "", false
+	}
+}
+
+// LookupStruct looks up variables from fields of struct v, v could be a pointer of struct.
+//
+// The variable name of a field is the name in `expand` tag, or the field name if no tag.
+// Fields tagged with `expand:"-"` are ignored.
+// Dotted names like ${db.host} look up nested structs, maps with string keys and
+// slice elements by index. Non-string values are formatted by fmt.Sprint,
+// nil pointers and interfaces are unset.
+func LookupStruct(v interface{}) VarLookup {
+	rv := reflect.ValueOf(v)
+	return func(varName string) (string, bool) {
+		cur := rv
+		for _, key := range strings.Split(varName, ".") {
+			var ok bool
+			if cur, ok = structChild(cur, key); !ok {
+				return "", false
+			}
+		}
+		cur, ok := indirectValue(cur)
+		if !ok {
+			return "", false
+		}
+		if cur.Kind() == reflect.String {
+			return cur.String(), true
+		}
+		return fmt.Sprint(cur), true
+	}
+}
+
+func indirectValue(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+func structChild(v reflect.Value, key string) (reflect.Value, bool) {
+	v, ok := indirectValue(v)
+	if !ok {
+		return v, false
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return structField(v, key)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return v, false
+		}
+		e := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		return e, e.IsValid()
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= v.Len() {
+			return v, false
+		}
+		return v.Index(i), true
+	}
+	return v, false
+}
+
+func structField(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	var embedded []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue // unexported
+		}
+		tag := f.Tag.Get("expand")
+		if tag == "-" {
+			continue
+		}
+		name := strings.SplitN(tag, ",", 2)[0]
+		if name == "" {
+			if f.Anonymous {
+				embedded = append(embedded, i)
+				continue
+			}
+			name = f.Name
+		}
+		if name == key {
+			return v.Field(i), true
+		}
+	}
+	for _, i := range embedded {
+		if fv, ok := structChild(v.Field(i), key); ok {
+			return fv, true
+		}
+	}
+	return v, false
+}
+
+// LookupJSON looks up variables from a JSON document.
+//
+// Dotted names like ${db.host} look up nested objects, and array elements by index,
+// e.g. ${servers.0}. Strings are returned without quotes, objects and arrays are
+// returned in compact JSON, null is unset.
+func LookupJSON(data []byte) (VarLookup, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return func(varName string) (string, bool) {
+		cur := doc
+		for _, key := range strings.Split(varName, ".") {
+			switch o := cur.(type) {
+			case map[string]interface{}:
+				cur = o[key]
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(o) {
+					return "", false
+				}
+				cur = o[i]
+			default:
+				return "", false
+			}
+		}
+		switch o := cur.(type) {
+		case nil:
+			return "", false
+		case string:
+			return o, true
+		case json.Number:
+			return o.String(), true
+		case bool:
+			return strconv.FormatBool(o), true
+		default:
+			return ToJSON(o), true
+		}
+	}, nil
+}
@@ -0,0 +1,71 @@
+package goutils
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestLookupEnvAndMap(t *testing.T) {
+	os.Setenv("GOUTILS_EXPAND_TEST", "env")
+	defer os.Unsetenv("GOUTILS_EXPAND_TEST")
+	l := LookupChain(LookupMap(map[string]string{"a": "map", "empty": ""}), LookupEnv())
+
+	got, err := ExpandLookup("${a} ${GOUTILS_EXPAND_TEST} ${empty-x} ${b:-default}", l)
+	assert.Nil(t, err)
+	assert.Equal(t, got, "map env  default")
+
+	got, err = Expand("${a} ${GOUTILS_EXPAND_TEST} ${b:-default}", l.Mapping())
+	assert.Nil(t, err)
+	assert.Equal(t, got, "map env default")
+}
+
+type lookupCommon struct {
+	Region string
+}
+
+type lookupDB struct {
+	Host string `expand:"host"`
+	Port int    `expand:"port"`
+}
+
+type lookupConfig struct {
+	lookupCommon
+	Name    string
+	DB      *lookupDB         `expand:"db"`
+	Backup  *lookupDB         `expand:"backup"`
+	Tags    []string          `expand:"tags"`
+	Labels  map[string]string `expand:"labels"`
+	Secret  string            `expand:"-"`
+	private string
+}
+
+func TestLookupStruct(t *testing.T) {
+	c := &lookupConfig{
+		lookupCommon: lookupCommon{Region: "cn"},
+		Name:         "app",
+		DB:           &lookupDB{Host: "localhost", Port: 3306},
+		Tags:         []string{"a", "b"},
+		Labels:       map[string]string{"env": "prod"},
+		Secret:       "password",
+	}
+	got, err := ExpandLookup("${Name} ${db.host}:${db.port} ${tags.1} ${labels.env} ${Region}", LookupStruct(c))
+	assert.Nil(t, err)
+	assert.Equal(t, got, "app localhost:3306 b prod cn")
+
+	got, err = ExpandLookup("${Secret-x}${private-x}${backup.host-x}${tags.2-x}${db.none-x}", LookupStruct(c))
+	assert.Nil(t, err)
+	assert.Equal(t, got, "xxxxx")
+}
+
+func TestLookupJSON(t *testing.T) {
+	l, err := LookupJSON([]byte(`{"db":{"host":"localhost","port":3306,"tls":true},"servers":["a","b"],"none":null}`))
+	assert.Nil(t, err)
+	got, err := ExpandLookup("${db.host}:${db.port} ${db.tls} ${servers.0} ${servers} ${none-x}${servers.5-x}", l)
+	assert.Nil(t, err)
+	assert.Equal(t, got, `localhost:3306 true a ["a","b"] xx`)
+
+	_, err = LookupJSON([]byte(`{`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,182 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// PanicError is recorded when a function panics in FanOut.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// FanOutOption configures FanOut.
+type FanOutOption interface {
+	apply(*fanOutOption)
+}
+
+type fanOutOption struct {
+	limit    int
+	timeout  time.Duration
+	failFast bool
+	multiErr []MultiErrOption
+}
+
+type fanOutOptionFunc func(*fanOutOption)
+
+func (f fanOutOptionFunc) apply(o *fanOutOption) {
+	f(o)
+}
+
+// FanOutLimit runs at most n ids at the same time, zero means no limit.
+func FanOutLimit(n int) FanOutOption {
+	return fanOutOptionFunc(func(o *fanOutOption) {
+		o.limit = n
+	})
+}
+
+// FanOutTimeout cancels the context passed to the function of each id after d.
+func FanOutTimeout(d time.Duration) FanOutOption {
+	return fanOutOptionFunc(func(o *fanOutOption) {
+		o.timeout = d
+	})
+}
+
+// FanOutFailFast cancels running ids and skips the rest after the first error,
+// by default all ids are run and all errors are collected.
+// Running ids fail with context.Canceled after that are listed in FanOutResult.Canceled
+// instead of Errs, so the errors that stop the run are not buried.
+func FanOutFailFast() FanOutOption {
+	return fanOutOptionFunc(func(o *fanOutOption) {
+		o.failFast = true
+	})
+}
+
+// FanOutMultiErr configures the MultiErr of the result.
+func FanOutMultiErr(opts ...MultiErrOption) FanOutOption {
+	return fanOutOptionFunc(func(o *fanOutOption) {
+		o.multiErr = append(o.multiErr, opts...)
+	})
+}
+
+// FanOutResult is the result of FanOut.
+type FanOutResult struct {
+	Errs      *MultiErr                // errors of run ids
+	Durations map[string]time.Duration // durations of run ids
+	Canceled  []string                 // run ids canceled by fail-fast, their errors are not in Errs
+	Skipped   []string                 // ids not run because of fail-fast or the canceled context
+	ctxErr    error
+}
+
+// Err returns the error of Errs, or the context error if ids are skipped without any failure.
+func (r *FanOutResult) Err() error {
+	if err := r.Errs.Error(); err != nil {
+		return err
+	}
+	return r.ctxErr
+}
+
+// FanOut calls f with each id concurrently, and waits all calls done.
+//
+// f should return soon after ctx is done, FanOutTimeout and FanOutFailFast
+// work by canceling ctx. A panic in f is recovered and recorded as a *PanicError.
+// Ids should be unique.
+func FanOut(ctx context.Context, ids []string, f func(ctx context.Context, id string) error,
+	opts ...FanOutOption) *FanOutResult {
+	var o fanOutOption
+	for _, a := range opts {
+		a.apply(&o)
+	}
+	r := &FanOutResult{
+		Errs:      NewMultiErr(o.multiErr...),
+		Durations: make(map[string]time.Duration, len(ids)),
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := o.limit
+	if limit <= 0 || limit > len(ids) {
+		limit = len(ids)
+	}
+	sem := make(chan struct{}, limit)
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		stopped  bool
+		canceled = make([]bool, len(ids))
+	)
+	for i, id := range ids {
+		acquired := false
+		select {
+		case sem <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		if !acquired || ctx.Err() != nil {
+			if acquired {
+				<-sem
+			}
+			r.Skipped = append(r.Skipped, ids[i:]...)
+			break
+		}
+		wg.Add(1)
+		go func(i int, id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			start := time.Now()
+			err := fanOutCall(ctx, id, f, o.timeout)
+			d := time.Since(start)
+			mu.Lock()
+			r.Durations[id] = d
+			canceled[i] = err != nil && stopped && errors.Is(err, context.Canceled)
+			if err != nil && o.failFast {
+				stopped = true
+			}
+			mu.Unlock()
+			if canceled[i] {
+				return
+			}
+			r.Errs.Set(id, err)
+			if err != nil && o.failFast {
+				cancel()
+			}
+		}(i, id)
+	}
+	wg.Wait()
+	for i, ok := range canceled {
+		if ok {
+			r.Canceled = append(r.Canceled, ids[i])
+		}
+	}
+	if len(r.Skipped) > 0 {
+		r.ctxErr = parent.Err()
+	}
+	return r
+}
+
+func fanOutCall(ctx context.Context, id string, f func(ctx context.Context, id string) error,
+	timeout time.Duration) (err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return f(ctx, id)
+}
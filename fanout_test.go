@@ -0,0 +1,103 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func fanOutIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+	}
+	return ids
+}
+
+func TestFanOut(t *testing.T) {
+	var running, peak int32
+	r := FanOut(context.Background(), fanOutIDs(20), func(ctx context.Context, id string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		switch id {
+		case "3":
+			return errors.New("fail 3")
+		case "5":
+			panic("boom")
+		}
+		return nil
+	}, FanOutLimit(4), FanOutMultiErr(MultiErrSorted()))
+
+	assert.True(t, atomic.LoadInt32(&peak) <= 4)
+	assert.Equal(t, len(r.Durations), 20)
+	assert.True(t, r.Durations["0"] >= time.Millisecond)
+	assert.Equal(t, len(r.Skipped), 0)
+	assert.Equal(t, r.Errs.Fails(), []string{"3", "5"})
+	assert.Equal(t, len(r.Errs.Successes()), 18)
+
+	var pe *PanicError
+	assert.True(t, errors.As(r.Err(), &pe))
+	assert.Equal(t, pe.Value, "boom")
+	assert.True(t, strings.Contains(string(pe.Stack), "fanout_test.go"))
+	assert.True(t, strings.HasPrefix(r.Err().Error(), "3:fail 3;5:panic: boom"))
+}
+
+func TestFanOutTimeout(t *testing.T) {
+	r := FanOut(context.Background(), []string{"fast", "slow"}, func(ctx context.Context, id string) error {
+		if id == "fast" {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, FanOutTimeout(10*time.Millisecond))
+	assert.Equal(t, r.Errs.Fails(), []string{"slow"})
+	assert.True(t, errors.Is(r.Err(), context.DeadlineExceeded))
+	id, ok := r.Err().(*MultiError).Find(context.DeadlineExceeded)
+	assert.True(t, ok)
+	assert.Equal(t, id, "slow")
+}
+
+func TestFanOutFailFast(t *testing.T) {
+	var calls int32
+	r := FanOut(context.Background(), fanOutIDs(100), func(ctx context.Context, id string) error {
+		atomic.AddInt32(&calls, 1)
+		if id == "1" {
+			return errors.New("fail")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}, FanOutLimit(2), FanOutFailFast())
+	assert.Equal(t, atomic.LoadInt32(&calls), int32(2))
+	assert.Equal(t, len(r.Skipped), 98)
+	assert.Equal(t, len(r.Durations), 2)
+	assert.Equal(t, r.Canceled, []string{"0"})
+	assert.Equal(t, r.Errs.Fails(), []string{"1"})
+	assert.Equal(t, len(r.Errs.Successes()), 0)
+	assert.Equal(t, r.Err().Error(), "1:fail")
+	assert.False(t, errors.Is(r.Err(), context.Canceled))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = FanOut(ctx, fanOutIDs(3), func(ctx context.Context, id string) error {
+		return nil
+	})
+	assert.Equal(t, r.Skipped, []string{"0", "1", "2"})
+	assert.Equal(t, r.Err(), context.Canceled)
+}
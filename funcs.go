@@ -1,29 +1,24 @@
 package goutils
 
 import (
-	"reflect"
 	"strings"
-	"unsafe"
 )
 
-// Isin return true if the element is in the slice.
-func Isin(s []string, elem string) bool {
-	for _, ss := range s {
-		if ss == elem {
-			return true
-		}
-	}
-	return false
-}
-
-// InplaceStringToSlice transfer string into []byte inplace
+// InplaceStringToSlice transfer string into []byte inplace, the capacity of result equals to its length.
+//
+// The result must not be modified.
+// Build with the goutils_debug tag to detect modification, the result is a checked copy then.
+// The detection is best-effort and delayed: the copy is checked by a finalizer when it's
+// garbage collected, which may be long after the modification or never if the program exits,
+// and the panic is raised on the finalizer goroutine, not where the bytes are modified.
 func InplaceStringToSlice(s string) []byte {
-	return *(*[]byte)(unsafe.Pointer((*reflect.SliceHeader)(unsafe.Pointer(&s))))
+	return stringToBytes(s)
 }
 
-// InplaceSliceToString transfer []byte into string inplace
+// InplaceSliceToString transfer []byte into string inplace.
+// The slice must not be modified while the result is in use.
 func InplaceSliceToString(s []byte) string {
-	return *(*string)(unsafe.Pointer(&s))
+	return unsafeBytesToString(s)
 }
 
 // ShortcutUTF8 return a valid UTF-8 string with at most `max` + len(suffix) characters.
@@ -31,6 +26,7 @@ func InplaceSliceToString(s []byte) string {
 // If `s` has more than `max` characters, cuts it to max and add `suffix`.
 // Multibyte characters are handled resonable.
 // If `max` is lower than 0, then return `s`.
+// See Truncate for grapheme or display width aware truncation.
 func ShortcutUTF8(s string, max int, suffix string) string {
 	if max < 0 {
 		return s
@@ -1,6 +1,10 @@
 package goutils
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
 
 func TestShortcutUTF8(t *testing.T) {
 	cases := []struct {
@@ -55,3 +59,17 @@ func TestShortcutUTF8(t *testing.T) {
 		})
 	}
 }
+
+func TestInplaceConvert(t *testing.T) {
+	for _, s := range []string{"", "a", "中文字符"} {
+		b := InplaceStringToSlice(s)
+		assert.Equal(t, string(b), s)
+		assert.Equal(t, cap(b), len(s))
+		assert.Equal(t, InplaceSliceToString(b), s)
+	}
+	b := []byte("foobar")
+	s := InplaceSliceToString(b[:3])
+	assert.Equal(t, s, "foo")
+	b[0] = 'g'
+	assert.Equal(t, s, "goo")
+}
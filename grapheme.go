@@ -0,0 +1,215 @@
+package goutils
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Graphemes splits s into user-perceived characters (extended grapheme clusters).
+//
+// It follows the rules of UAX #29 for combining marks, Hangul syllables, regional indicators
+// and emoji ZWJ sequences, with Extended_Pictographic and SpacingMark approximated by tables
+// of this package. Invalid UTF-8 bytes are separate clusters.
+func Graphemes(s string) []string {
+	var r []string
+	for s != "" {
+		n := nextGrapheme(s)
+		r = append(r, s[:n])
+		s = s[n:]
+	}
+	return r
+}
+
+// StringWidth returns the number of terminal columns s occupies.
+//
+// East Asian wide and fullwidth characters and emoji occupy 2 columns, control characters and
+// combining marks occupy none, others including East Asian ambiguous characters occupy 1.
+func StringWidth(s string) (w int) {
+	for s != "" {
+		n := nextGrapheme(s)
+		w += graphemeWidth(s[:n])
+		s = s[n:]
+	}
+	return
+}
+
+const (
+	zwj  = '\u200d'
+	vs16 = '\ufe0f'
+)
+
+// nextGrapheme returns the byte length of the first grapheme cluster of s.
+func nextGrapheme(s string) int {
+	if s == "" {
+		return 0
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	if r == '\r' && len(s) > 1 && s[1] == '\n' {
+		return 2
+	}
+	if isGraphemeControl(r) {
+		return n
+	}
+	prev := r
+	pict := isPictographic(r)
+	regional := isRegional(r)
+	for n < len(s) {
+		c, m := utf8.DecodeRuneInString(s[n:])
+		switch {
+		case isGraphemeControl(c):
+			return n
+		case isGraphemeExtend(c):
+		case prev == zwj && pict && isPictographic(c):
+		case regional && isRegional(c):
+			regional = false
+		case hangulJoins(prev, c):
+			pict = false
+		default:
+			return n
+		}
+		prev = c
+		n += m
+	}
+	return n
+}
+
+// graphemeWidth returns the display width of a grapheme cluster.
+func graphemeWidth(g string) int {
+	r, n := utf8.DecodeRuneInString(g)
+	switch {
+	case isGraphemeControl(r), isGraphemeExtend(r):
+		return 0
+	case isRegional(r):
+		if len(g) > n {
+			return 2
+		}
+		return 1
+	case wideRunes.contains(r):
+		return 2
+	case isPictographic(r) && strings.ContainsRune(g[n:], vs16):
+		return 2
+	}
+	return 1
+}
+
+func isGraphemeControl(r rune) bool {
+	switch {
+	case r == '\u200c' || r == zwj:
+		return false
+	case r >= 0xe0020 && r <= 0xe007f:
+		// emoji tag sequences
+		return false
+	}
+	return r == utf8.RuneError || unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp)
+}
+
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case r == '\u200c' || r == zwj:
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff:
+		// emoji modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f:
+		return true
+	case r == 0xff9e || r == 0xff9f:
+		// halfwidth katakana sound marks
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
+
+func isRegional(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isPictographic(r rune) bool {
+	return pictographicRunes.contains(r)
+}
+
+const (
+	hangulL = iota + 1
+	hangulV
+	hangulT
+	hangulLV
+	hangulLVT
+)
+
+func hangulType(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115f, r >= 0xa960 && r <= 0xa97c:
+		return hangulL
+	case r >= 0x1160 && r <= 0x11a7, r >= 0xd7b0 && r <= 0xd7c6:
+		return hangulV
+	case r >= 0x11a8 && r <= 0x11ff, r >= 0xd7cb && r <= 0xd7fb:
+		return hangulT
+	case r >= 0xac00 && r <= 0xd7a3:
+		if (r-0xac00)%28 == 0 {
+			return hangulLV
+		}
+		return hangulLVT
+	}
+	return 0
+}
+
+func hangulJoins(a, b rune) bool {
+	tb := hangulType(b)
+	switch hangulType(a) {
+	case hangulL:
+		return tb == hangulL || tb == hangulV || tb == hangulLV || tb == hangulLVT
+	case hangulLV, hangulV:
+		return tb == hangulV || tb == hangulT
+	case hangulLVT, hangulT:
+		return tb == hangulT
+	}
+	return false
+}
+
+// runeRanges is a sorted list of inclusive rune ranges.
+type runeRanges [][2]rune
+
+func (rs runeRanges) contains(r rune) bool {
+	i := sort.Search(len(rs), func(i int) bool { return rs[i][1] >= r })
+	return i < len(rs) && rs[i][0] <= r
+}
+
+// wideRunes are East Asian Wide and Fullwidth characters.
+var wideRunes = runeRanges{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec}, {0x23f0, 0x23f0},
+	{0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267f, 0x267f},
+	{0x2693, 0x2693}, {0x26a1, 0x26a1}, {0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5},
+	{0x26ce, 0x26ce}, {0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b}, {0x2728, 0x2728},
+	{0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27b0, 0x27b0}, {0x27bf, 0x27bf}, {0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55},
+	{0x2e80, 0x303e}, {0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf},
+	{0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe10, 0xfe19}, {0xfe30, 0xfe6f},
+	{0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4}, {0x17000, 0x18aff}, {0x1b000, 0x1b2ff},
+	{0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf}, {0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f202},
+	{0x1f210, 0x1f23b}, {0x1f240, 0x1f248}, {0x1f250, 0x1f251}, {0x1f260, 0x1f265}, {0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335}, {0x1f337, 0x1f37c}, {0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca}, {0x1f3cf, 0x1f3d3},
+	{0x1f3e0, 0x1f3f0}, {0x1f3f4, 0x1f3f4}, {0x1f3f8, 0x1f43e}, {0x1f440, 0x1f440}, {0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e}, {0x1f550, 0x1f567}, {0x1f57a, 0x1f57a}, {0x1f595, 0x1f596},
+	{0x1f5a4, 0x1f5a4}, {0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5}, {0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7}, {0x1f6dc, 0x1f6df}, {0x1f6eb, 0x1f6ec}, {0x1f6f4, 0x1f6fc}, {0x1f7e0, 0x1f7eb},
+	{0x1f7f0, 0x1f7f0}, {0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945}, {0x1f947, 0x1f9ff}, {0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// pictographicRunes approximates the Extended_Pictographic property.
+var pictographicRunes = runeRanges{
+	{0x00a9, 0x00a9}, {0x00ae, 0x00ae}, {0x203c, 0x203c}, {0x2049, 0x2049}, {0x2122, 0x2122},
+	{0x2139, 0x2139}, {0x2194, 0x2199}, {0x21a9, 0x21aa}, {0x231a, 0x231b}, {0x2328, 0x2328},
+	{0x2388, 0x2388}, {0x23cf, 0x23cf}, {0x23e9, 0x23f3}, {0x23f8, 0x23fa}, {0x24c2, 0x24c2},
+	{0x25aa, 0x25ab}, {0x25b6, 0x25b6}, {0x25c0, 0x25c0}, {0x25fb, 0x25fe}, {0x2600, 0x27bf},
+	{0x2934, 0x2935}, {0x2b05, 0x2b07}, {0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55},
+	{0x3030, 0x3030}, {0x303d, 0x303d}, {0x3297, 0x3297}, {0x3299, 0x3299}, {0x1f000, 0x1f0ff},
+	{0x1f10d, 0x1f10f}, {0x1f12f, 0x1f12f}, {0x1f16c, 0x1f171}, {0x1f17e, 0x1f17f}, {0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a}, {0x1f1ad, 0x1f1e5}, {0x1f201, 0x1f20f}, {0x1f21a, 0x1f21a}, {0x1f22f, 0x1f22f},
+	{0x1f232, 0x1f23a}, {0x1f23c, 0x1f23f}, {0x1f249, 0x1f3fa}, {0x1f400, 0x1f53d}, {0x1f546, 0x1f64f},
+	{0x1f680, 0x1f6ff}, {0x1f774, 0x1f77f}, {0x1f7d5, 0x1f7ff}, {0x1f80c, 0x1f80f}, {0x1f848, 0x1f84f},
+	{0x1f85a, 0x1f85f}, {0x1f888, 0x1f88f}, {0x1f8ae, 0x1f8ff}, {0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945},
+	{0x1f947, 0x1faff}, {0x1fc00, 0x1fffd},
+}
@@ -0,0 +1,85 @@
+package goutils
+
+import (
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestGraphemes(t *testing.T) {
+	cases := []struct {
+		s     string
+		want  []string
+		width int
+	}{
+		{"abc", []string{"a", "b", "c"}, 3},
+		{"中文", []string{"中", "文"}, 4},
+		{"éx", []string{"é", "x"}, 2},
+		{"\r\n\n", []string{"\r\n", "\n"}, 0},
+		{"👩‍👩‍👧!", []string{"👩‍👩‍👧", "!"}, 3},
+		{"👍🏽", []string{"👍🏽"}, 2},
+		{"❤️❤", []string{"❤️", "❤"}, 3},
+		{"🇨🇳🇺🇸🇯", []string{"🇨🇳", "🇺🇸", "🇯"}, 5},
+		{"각한", []string{"각", "한"}, 4},
+		{"ｶﾞ", []string{"ｶﾞ"}, 1},
+		{"\xff́", []string{"\xff", "́"}, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.s, func(t *testing.T) {
+			assert.Equal(t, Graphemes(c.s), c.want)
+			assert.Equal(t, StringWidth(c.s), c.width)
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		name   string
+		s      string
+		max    int
+		suffix string
+		opts   []TruncateOption
+		want   string
+	}{
+		{"fit", "foobar", 6, "...", nil, "foobar"},
+		{"runes", "foobar", 5, "...", nil, "fo..."},
+		{"negative", "foobar", -1, "...", nil, "foobar"},
+		{"long suffix", "foobar", 2, "...", nil, ".."},
+		{"zero", "foobar", 0, "...", nil, ""},
+		{"runes split", "ééé", 4, "…", nil, "ée…"},
+		{"graphemes", "ééé", 2, "…", []TruncateOption{TruncateGraphemes()}, "é…"},
+		{"emoji", "👩‍👩‍👧👍🏽ok", 3, "…", []TruncateOption{TruncateGraphemes()}, "👩‍👩‍👧👍🏽…"},
+		{"width", "中文字符", 5, "…", []TruncateOption{TruncateWidth()}, "中文…"},
+		{"width odd", "中文字符", 6, "…", []TruncateOption{TruncateWidth()}, "中文…"},
+		{"width fit", "中文字符", 8, "…", []TruncateOption{TruncateWidth()}, "中文字符"},
+		{"width wide suffix", "中文字符", 7, "。", []TruncateOption{TruncateWidth()}, "中文。"},
+		{"middle", "abcdefghij", 7, "…", []TruncateOption{TruncateMiddle()}, "abc…hij"},
+		{"middle even", "abcdefghij", 6, "…", []TruncateOption{TruncateMiddle()}, "abc…ij"},
+		{"middle width", "中文abc字符", 7, "…", []TruncateOption{TruncateMiddle(), TruncateWidth()}, "中…字符"},
+		{"middle tiny", "abcdefghij", 1, "…", []TruncateOption{TruncateMiddle()}, "…"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := Truncate(c.s, c.max, c.suffix, c.opts...)
+			assert.Equal(t, got, c.want)
+		})
+	}
+}
+
+func TestTruncateWidthLimit(t *testing.T) {
+	s := "a中👩‍👩‍👧b🇨🇳é文c"
+	for _, suffix := range []string{"", "…", "。", "..."} {
+		for max := 0; max <= StringWidth(s)+1; max++ {
+			for _, middle := range []bool{false, true} {
+				opts := []TruncateOption{TruncateWidth()}
+				if middle {
+					opts = append(opts, TruncateMiddle())
+				}
+				got := Truncate(s, max, suffix, opts...)
+				if StringWidth(got) > max {
+					t.Fatalf("Truncate(%q, %d, %q, middle=%v) = %q", s, max, suffix, middle, got)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package humanize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	siBytes  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	iecBytes = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+// FormatBytes formats n in SI units, e.g. FormatBytes(1500000) returns "1.5 MB".
+func FormatBytes(n uint64, opts ...Option) string {
+	return formatScaled(float64(n), 1000, siBytes, 0, " ", newOptions(opts))
+}
+
+// FormatIBytes formats n in IEC units, e.g. FormatIBytes(1572864) returns "1.5 MiB".
+func FormatIBytes(n uint64, opts ...Option) string {
+	return formatScaled(float64(n), 1024, iecBytes, 0, " ", newOptions(opts))
+}
+
+// ParseBytes parses a byte size in SI or IEC units, e.g. "1.5 MB", "1.5MiB", "42".
+//
+// Units are case-insensitive, "K", "M" ... are SI units, "Ki", "Mi" ... are IEC units.
+// Fractional bytes are rounded to the nearest integer.
+func ParseBytes(s string) (uint64, error) {
+	num, unit := splitNumber(strings.TrimSpace(s))
+	if num == "" || num[0] == '-' || num[0] == '+' {
+		return 0, fmt.Errorf("humanize: parse bytes %q: %w", s, ErrSyntax)
+	}
+	mul, ok := byteUnit(unit)
+	if !ok {
+		return 0, fmt.Errorf("humanize: parse bytes %q: unknown unit %q: %w", s, unit, ErrSyntax)
+	}
+	if !strings.Contains(num, ".") {
+		v, err := strconv.ParseUint(num, 10, 64)
+		if err == nil && v <= math.MaxUint64/mul {
+			return v * mul, nil
+		}
+		if err != nil && !isRangeError(err) {
+			return 0, fmt.Errorf("humanize: parse bytes %q: %w", s, ErrSyntax)
+		}
+		return 0, fmt.Errorf("humanize: parse bytes %q: %w", s, ErrRange)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("humanize: parse bytes %q: %w", s, ErrSyntax)
+	}
+	f = math.Round(f * float64(mul))
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("humanize: parse bytes %q: %w", s, ErrRange)
+	}
+	return uint64(f), nil
+}
+
+func byteUnit(unit string) (uint64, bool) {
+	unit = strings.ToLower(unit)
+	if unit == "" || unit == "b" {
+		return 1, true
+	}
+	unit = strings.TrimSuffix(unit, "b")
+	base := uint64(1000)
+	if strings.HasSuffix(unit, "i") {
+		base = 1024
+		unit = unit[:len(unit)-1]
+	}
+	i := strings.Index("kmgtpe", unit)
+	if len(unit) != 1 || i < 0 {
+		return 0, false
+	}
+	mul := uint64(1)
+	for ; i >= 0; i-- {
+		mul *= base
+	}
+	return mul, true
+}
+
+func isRangeError(err error) bool {
+	ne, ok := err.(*strconv.NumError)
+	return ok && ne.Err == strconv.ErrRange
+}
@@ -0,0 +1,69 @@
+package humanize_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+	"github.com/hanke0/goutils/humanize"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := []struct {
+		n    uint64
+		opts []humanize.Option
+		si   string
+		iec  string
+	}{
+		{0, nil, "0 B", "0 B"},
+		{999, nil, "999 B", "999 B"},
+		{1000, nil, "1 kB", "1000 B"},
+		{1024, nil, "1 kB", "1 KiB"},
+		{1500000, nil, "1.5 MB", "1.4 MiB"},
+		{1572864, nil, "1.6 MB", "1.5 MiB"},
+		{999960, nil, "1 MB", "976.5 KiB"},
+		{1234567, []humanize.Option{humanize.Precision(3)}, "1.235 MB", "1.177 MiB"},
+		{1299999, []humanize.Option{humanize.Rounding(humanize.RoundDown)}, "1.2 MB", "1.2 MiB"},
+		{1200001, []humanize.Option{humanize.Rounding(humanize.RoundUp)}, "1.3 MB", "1.2 MiB"},
+		{1250000, []humanize.Option{humanize.Rounding(humanize.RoundHalfEven)}, "1.2 MB", "1.2 MiB"},
+		{math.MaxUint64, nil, "18.4 EB", "16 EiB"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, humanize.FormatBytes(c.n, c.opts...), c.si)
+		assert.Equal(t, humanize.FormatIBytes(c.n, c.opts...), c.iec)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	cases := []struct {
+		s    string
+		want uint64
+		err  error
+	}{
+		{"42", 42, nil},
+		{"42 B", 42, nil},
+		{"1.5 MB", 1500000, nil},
+		{"1.5MiB", 1572864, nil},
+		{"1 k", 1000, nil},
+		{"2Ki", 2048, nil},
+		{" 3 gb ", 3000000000, nil},
+		{"16 EiB", 0, humanize.ErrRange},
+		{"18446744073709551615", math.MaxUint64, nil},
+		{"18446744073709551616", 0, humanize.ErrRange},
+		{"-1 MB", 0, humanize.ErrSyntax},
+		{"1 XB", 0, humanize.ErrSyntax},
+		{"MB", 0, humanize.ErrSyntax},
+		{"1..2 MB", 0, humanize.ErrSyntax},
+	}
+	for _, c := range cases {
+		got, err := humanize.ParseBytes(c.s)
+		assert.Equal(t, got, c.want)
+		assert.True(t, errors.Is(err, c.err))
+	}
+	for _, n := range []uint64{0, 1, 1024, 1572864, 2 << 40} {
+		got, err := humanize.ParseBytes(humanize.FormatIBytes(n))
+		assert.Nil(t, err)
+		assert.Equal(t, got, n)
+	}
+}
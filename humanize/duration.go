@@ -0,0 +1,163 @@
+package humanize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationUnits = []struct {
+	name string
+	size uint64
+}{
+	{"d", uint64(24 * time.Hour)},
+	{"h", uint64(time.Hour)},
+	{"m", uint64(time.Minute)},
+	{"s", uint64(time.Second)},
+	{"ms", uint64(time.Millisecond)},
+	{"µs", uint64(time.Microsecond)},
+	{"ns", uint64(time.Nanosecond)},
+}
+
+// FormatDuration formats d in a compact form with at most 2 units, e.g. "3h2m", "1d4h", "1.5s"
+// is formatted as "1s500ms". Units changes the number of units, the last unit is rounded
+// without exceeding the range of time.Duration, so the result is always parsed by ParseDuration.
+func FormatDuration(d time.Duration, opts ...Option) string {
+	if d == 0 {
+		return "0s"
+	}
+	o := newOptions(opts)
+	var b strings.Builder
+	u := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	_, last := durationRange(u, o.units)
+	if r := roundUint(u, durationUnits[last].size, o.rounding); r <= durationLimit(d < 0) {
+		u = r
+	} else {
+		// Keeps the result in the range of time.Duration, so that it could be parsed.
+		u = roundUint(u, durationUnits[last].size, RoundDown)
+	}
+	first, last := durationRange(u, o.units)
+	for i := first; i <= last; i++ {
+		unit := durationUnits[i]
+		if n := u / unit.size; n > 0 {
+			b.WriteString(strconv.FormatUint(n, 10))
+			b.WriteString(unit.name)
+		}
+		u %= unit.size
+	}
+	return b.String()
+}
+
+// durationLimit returns the max absolute value of time.Duration.
+func durationLimit(neg bool) uint64 {
+	if neg {
+		return math.MaxInt64 + 1
+	}
+	return math.MaxInt64
+}
+
+func durationRange(u uint64, n int) (first, last int) {
+	for first < len(durationUnits)-1 && u < durationUnits[first].size {
+		first++
+	}
+	last = first + n - 1
+	if last >= len(durationUnits) {
+		last = len(durationUnits) - 1
+	}
+	return
+}
+
+func roundUint(u, unit uint64, mode RoundingMode) uint64 {
+	q, r := u/unit, u%unit
+	switch {
+	case r == 0:
+	case mode == RoundDown:
+	case mode == RoundUp,
+		mode == RoundHalfEven && (r > unit-r || r == unit-r && q%2 == 1),
+		mode == RoundNearest && r >= unit-r:
+		q++
+	}
+	return q * unit
+}
+
+// ParseDuration parses a duration like time.ParseDuration, and accepts "d" for 24 hours,
+// e.g. "1d4h", "3h2m", "1.5s", "-2m".
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("humanize: parse duration %q: %w", orig, ErrSyntax)
+	}
+	var total uint64
+	limit := durationLimit(neg)
+	for s != "" {
+		i := 0
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+			i++
+		}
+		num := s[:i]
+		s = s[i:]
+		i = 0
+		for i < len(s) && !(s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+			i++
+		}
+		unit, ok := parseDurationUnit(s[:i])
+		s = s[i:]
+		if !ok || num == "" || num == "." {
+			return 0, fmt.Errorf("humanize: parse duration %q: %w", orig, ErrSyntax)
+		}
+		var v uint64
+		if strings.IndexByte(num, '.') < 0 {
+			// Integers are parsed exactly.
+			n, err := strconv.ParseUint(num, 10, 64)
+			if err != nil || n > limit/unit {
+				return 0, fmt.Errorf("humanize: parse duration %q: %w", orig, ErrRange)
+			}
+			v = n * unit
+		} else {
+			f, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("humanize: parse duration %q: %w", orig, ErrSyntax)
+			}
+			f = math.Round(f * float64(unit))
+			if f > float64(limit) {
+				return 0, fmt.Errorf("humanize: parse duration %q: %w", orig, ErrRange)
+			}
+			v = uint64(f)
+		}
+		if v > limit-total {
+			return 0, fmt.Errorf("humanize: parse duration %q: %w", orig, ErrRange)
+		}
+		total += v
+	}
+	if neg {
+		return time.Duration(-total), nil
+	}
+	return time.Duration(total), nil
+}
+
+func parseDurationUnit(name string) (uint64, bool) {
+	switch name {
+	case "us", "μs":
+		name = "µs"
+	}
+	for _, u := range durationUnits {
+		if u.name == name {
+			return u.size, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package humanize_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hanke0/goutils/assert"
+	"github.com/hanke0/goutils/humanize"
+)
+
+func TestFormatDuration(t *testing.T) {
+	cases := []struct {
+		d    time.Duration
+		opts []humanize.Option
+		want string
+	}{
+		{0, nil, "0s"},
+		{time.Nanosecond, nil, "1ns"},
+		{3*time.Hour + 2*time.Minute + 15*time.Second, nil, "3h2m"},
+		{3*time.Hour + 2*time.Minute + 45*time.Second, nil, "3h3m"},
+		{3*time.Hour + 2*time.Minute + 45*time.Second, []humanize.Option{humanize.Rounding(humanize.RoundDown)}, "3h2m"},
+		{3*time.Hour + 2*time.Minute + 45*time.Second, []humanize.Option{humanize.Units(3)}, "3h2m45s"},
+		{3*time.Hour + 45*time.Second, []humanize.Option{humanize.Units(3)}, "3h45s"},
+		{28 * time.Hour, nil, "1d4h"},
+		{59*time.Minute + 59*time.Second + 600*time.Millisecond, nil, "1h"},
+		{1500 * time.Millisecond, []humanize.Option{humanize.Units(1)}, "2s"},
+		{1500 * time.Millisecond, []humanize.Option{humanize.Units(1), humanize.Rounding(humanize.RoundHalfEven)}, "2s"},
+		{2500 * time.Millisecond, []humanize.Option{humanize.Units(1), humanize.Rounding(humanize.RoundHalfEven)}, "2s"},
+		{2100 * time.Millisecond, []humanize.Option{humanize.Units(1), humanize.Rounding(humanize.RoundUp)}, "3s"},
+		{-90 * time.Second, nil, "-1m30s"},
+		{1500 * time.Microsecond, nil, "1ms500µs"},
+		{math.MinInt64, nil, "-106751d23h"},
+		{math.MaxInt64, nil, "106751d23h"},
+		{math.MaxInt64, []humanize.Option{humanize.Units(7), humanize.Rounding(humanize.RoundUp)}, "106751d23h47m16s854ms775µs807ns"},
+		{math.MaxInt64, []humanize.Option{humanize.Units(3), humanize.Rounding(humanize.RoundUp)}, "106751d23h47m"},
+		{math.MinInt64, []humanize.Option{humanize.Rounding(humanize.RoundDown)}, "-106751d23h"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, humanize.FormatDuration(c.d, c.opts...), c.want)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		s    string
+		want time.Duration
+		err  error
+	}{
+		{"0", 0, nil},
+		{"1d4h", 28 * time.Hour, nil},
+		{"3h2m", 3*time.Hour + 2*time.Minute, nil},
+		{"1.5s", 1500 * time.Millisecond, nil},
+		{"-2m", -2 * time.Minute, nil},
+		{"1ms500us", 1500 * time.Microsecond, nil},
+		{"1ms500µs", 1500 * time.Microsecond, nil},
+		{"200000d", 0, humanize.ErrRange},
+		{"-9223372036854775808ns", math.MinInt64, nil},
+		{"9223372036854775807ns", math.MaxInt64, nil},
+		{"9223372036854775808ns", 0, humanize.ErrRange},
+		{"106751d23h47m16s854ms775µs808ns", 0, humanize.ErrRange},
+		{"-106751d23h47m16s854ms775µs808ns", math.MinInt64, nil},
+		{"99999999999999999999ns", 0, humanize.ErrRange},
+		{"106752d", 0, humanize.ErrRange},
+		{"", 0, humanize.ErrSyntax},
+		{"1", 0, humanize.ErrSyntax},
+		{"1y", 0, humanize.ErrSyntax},
+		{"h", 0, humanize.ErrSyntax},
+	}
+	for _, c := range cases {
+		got, err := humanize.ParseDuration(c.s)
+		assert.Equal(t, got, c.want)
+		assert.True(t, errors.Is(err, c.err))
+	}
+	for _, d := range []time.Duration{time.Second, -28 * time.Hour, 1500 * time.Microsecond} {
+		got, err := humanize.ParseDuration(humanize.FormatDuration(d))
+		assert.Nil(t, err)
+		assert.Equal(t, got, d)
+	}
+	for _, d := range []time.Duration{math.MaxInt64, math.MinInt64} {
+		for _, mode := range []humanize.RoundingMode{humanize.RoundNearest, humanize.RoundUp, humanize.RoundHalfEven} {
+			_, err := humanize.ParseDuration(humanize.FormatDuration(d, humanize.Rounding(mode)))
+			assert.Nil(t, err)
+		}
+		got, err := humanize.ParseDuration(humanize.FormatDuration(d, humanize.Units(7)))
+		assert.Nil(t, err)
+		assert.Equal(t, got, d)
+	}
+}
@@ -0,0 +1,156 @@
+// Package humanize formats and parses byte sizes, durations and numbers for human.
+//
+// Outputs are locale-independent and could be parsed by the corresponding Parse functions.
+package humanize // import "github.com/hanke0/goutils/humanize"
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrSyntax indicates that a value does not have the right syntax.
+	ErrSyntax = errors.New("invalid syntax")
+	// ErrRange indicates that a value is out of range.
+	ErrRange = errors.New("value out of range")
+)
+
+// RoundingMode decides how values are rounded to the precision.
+type RoundingMode int
+
+const (
+	// RoundNearest rounds half away from zero, it's the default.
+	RoundNearest RoundingMode = iota
+	// RoundHalfEven rounds half to even.
+	RoundHalfEven
+	// RoundDown rounds toward zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+)
+
+// Option configures format functions.
+type Option interface {
+	apply(*options)
+}
+
+type options struct {
+	precision int
+	rounding  RoundingMode
+	units     int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{precision: 1, units: 2}
+	for _, a := range opts {
+		a.apply(o)
+	}
+	return o
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+// Precision sets the max number of decimal places of byte sizes and numbers, default is 1.
+// Trailing zeros are always removed.
+func Precision(n int) Option {
+	return optionFunc(func(o *options) {
+		if n < 0 {
+			n = 0
+		}
+		o.precision = n
+	})
+}
+
+// Rounding sets the rounding mode, default is RoundNearest.
+func Rounding(mode RoundingMode) Option {
+	return optionFunc(func(o *options) {
+		o.rounding = mode
+	})
+}
+
+// Units sets the max number of units of durations, e.g. 3h2m has 2 units, default is 2.
+func Units(n int) Option {
+	return optionFunc(func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.units = n
+	})
+}
+
+// round rounds x to prec decimal places.
+func round(x float64, prec int, mode RoundingMode) float64 {
+	pow := math.Pow10(prec)
+	v := x * pow
+	if r := math.Round(v); math.Abs(v-r) <= 1e-9*math.Abs(v) {
+		// Drops the float error of x * pow, e.g. 1.15 * 100 = 114.99999999999999.
+		v = r
+	}
+	switch mode {
+	case RoundHalfEven:
+		v = math.RoundToEven(v)
+	case RoundDown:
+		v = math.Trunc(v)
+	case RoundUp:
+		if v < 0 {
+			v = math.Floor(v)
+		} else {
+			v = math.Ceil(v)
+		}
+	default:
+		v = math.Round(v)
+	}
+	return v / pow
+}
+
+// formatScaled formats v with the largest unit that keeps the mantissa lower than base.
+// units[zero] is the unit of exponent 0.
+func formatScaled(v float64, base float64, units []string, zero int, sep string, o *options) string {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return formatFloat(v, 0) + sep + units[zero]
+	}
+	exp := int(math.Floor(math.Log(math.Abs(v)) / math.Log(base)))
+	if exp < -zero {
+		exp = -zero
+	}
+	if exp > len(units)-1-zero {
+		exp = len(units) - 1 - zero
+	}
+	m := round(v/math.Pow(base, float64(exp)), o.precision, o.rounding)
+	// Rounding may carry to the next unit, e.g. 999.96 -> 1000.0 -> 1k.
+	for math.Abs(m) >= base && exp < len(units)-1-zero {
+		exp++
+		m = round(v/math.Pow(base, float64(exp)), o.precision, o.rounding)
+	}
+	return formatFloat(m, o.precision) + sep + units[exp+zero]
+}
+
+func formatFloat(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+// splitNumber splits s into a decimal number and the rest, spaces between them are removed.
+func splitNumber(s string) (num, unit string) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	return s[:i], strings.TrimLeft(s[i:], " ")
+}
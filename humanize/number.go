@@ -0,0 +1,91 @@
+package humanize
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var metricUnits = []string{"p", "n", "µ", "m", "", "k", "M", "G", "T", "P", "E"}
+
+const metricZero = 4
+
+// FormatMetric formats v with a metric suffix, e.g. FormatMetric(12345) returns "12.3k",
+// FormatMetric(0.0025) returns "2.5m".
+// Values out of the prefixes are clamped to the smallest or largest prefix, e.g.
+// FormatMetric(1e-20) returns "0p" and FormatMetric(1e21) returns "1000E".
+func FormatMetric(v float64, opts ...Option) string {
+	return formatScaled(v, 1000, metricUnits, metricZero, "", newOptions(opts))
+}
+
+// ParseMetric parses a number with an optional metric suffix, e.g. "12.3k", "2.5m", "-1 M".
+// Both "K" and "k" mean 1000, "u" is the same as "µ".
+func ParseMetric(s string) (float64, error) {
+	num, unit := splitNumber(strings.TrimSpace(s))
+	switch unit {
+	case "K":
+		unit = "k"
+	case "u", "μ":
+		unit = "µ"
+	}
+	exp, found := 0, false
+	for i, u := range metricUnits {
+		if u == unit {
+			exp, found = i-metricZero, true
+		}
+	}
+	if !found || num == "" {
+		return 0, fmt.Errorf("humanize: parse metric %q: %w", s, ErrSyntax)
+	}
+	f, err := strconv.ParseFloat(num+"e"+strconv.Itoa(exp*3), 64)
+	if err != nil {
+		if isRangeError(err) {
+			return 0, fmt.Errorf("humanize: parse metric %q: %w", s, ErrRange)
+		}
+		return 0, fmt.Errorf("humanize: parse metric %q: %w", s, ErrSyntax)
+	}
+	return f, nil
+}
+
+// Ordinal returns n with the english ordinal suffix, e.g. "1st", "2nd", "3rd", "11th".
+func Ordinal(n int) string {
+	return strconv.Itoa(n) + ordinalSuffix(n)
+}
+
+// ParseOrdinal parses a number formatted by Ordinal.
+func ParseOrdinal(s string) (int, error) {
+	i := len(s) - 2
+	if i < 1 {
+		return 0, fmt.Errorf("humanize: parse ordinal %q: %w", s, ErrSyntax)
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		if isRangeError(err) {
+			return 0, fmt.Errorf("humanize: parse ordinal %q: %w", s, ErrRange)
+		}
+		return 0, fmt.Errorf("humanize: parse ordinal %q: %w", s, ErrSyntax)
+	}
+	if ordinalSuffix(n) != s[i:] {
+		return 0, fmt.Errorf("humanize: parse ordinal %q: %w", s, ErrSyntax)
+	}
+	return n, nil
+}
+
+func ordinalSuffix(n int) string {
+	if n < 0 {
+		n = -n
+	}
+	switch n % 100 {
+	case 11, 12, 13:
+		return "th"
+	}
+	switch n % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	}
+	return "th"
+}
@@ -0,0 +1,77 @@
+package humanize_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+	"github.com/hanke0/goutils/humanize"
+)
+
+func TestFormatMetric(t *testing.T) {
+	cases := []struct {
+		v    float64
+		opts []humanize.Option
+		want string
+	}{
+		{0, nil, "0"},
+		{999, nil, "999"},
+		{12345, nil, "12.3k"},
+		{-12345, nil, "-12.3k"},
+		{999960, nil, "1M"},
+		{0.0025, nil, "2.5m"},
+		{0.0000012, nil, "1.2µ"},
+		{1e-15, nil, "0p"},
+		{1e-20, nil, "0p"},
+		{-1e-20, nil, "0p"},
+		{1e21, nil, "1000E"},
+		{12345, []humanize.Option{humanize.Precision(0)}, "12k"},
+		{12399, []humanize.Option{humanize.Rounding(humanize.RoundDown)}, "12.3k"},
+		{math.Inf(1), nil, "+Inf"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, humanize.FormatMetric(c.v, c.opts...), c.want)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	cases := []struct {
+		s    string
+		want float64
+		err  error
+	}{
+		{"12.3k", 12300, nil},
+		{"12.3K", 12300, nil},
+		{"-1 M", -1e6, nil},
+		{"2.5m", 0.0025, nil},
+		{"1.2u", 1.2e-6, nil},
+		{"42", 42, nil},
+		{"1" + strings.Repeat("0", 300) + "E", 0, humanize.ErrRange},
+		{"1x", 0, humanize.ErrSyntax},
+		{"k", 0, humanize.ErrSyntax},
+	}
+	for _, c := range cases {
+		got, err := humanize.ParseMetric(c.s)
+		assert.Equal(t, got, c.want)
+		assert.True(t, errors.Is(err, c.err))
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	cases := map[int]string{
+		0: "0th", 1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th",
+		21: "21st", 102: "102nd", 111: "111th", -1: "-1st",
+	}
+	for n, s := range cases {
+		assert.Equal(t, humanize.Ordinal(n), s)
+		got, err := humanize.ParseOrdinal(s)
+		assert.Nil(t, err)
+		assert.Equal(t, got, n)
+	}
+	for _, s := range []string{"1th", "st", "2", "a2nd"} {
+		_, err := humanize.ParseOrdinal(s)
+		assert.True(t, errors.Is(err, humanize.ErrSyntax))
+	}
+}
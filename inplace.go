@@ -0,0 +1,8 @@
+//go:build !goutils_debug
+// +build !goutils_debug
+
+package goutils
+
+func stringToBytes(s string) []byte {
+	return unsafeStringToBytes(s)
+}
@@ -0,0 +1,46 @@
+//go:build goutils_debug
+// +build goutils_debug
+
+package goutils
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// inplaceMutated is called with a message when bytes from InplaceStringToSlice are modified.
+var inplaceMutated atomic.Value
+
+func init() {
+	inplaceMutated.Store(func(msg string) {
+		panic(msg)
+	})
+}
+
+const inplaceCheckMax = 1 << 30
+
+// stringToBytes returns a copy of s, and checks that the copy is not modified
+// before it is garbage collected. A modification is reported by the finalizer,
+// which runs at some later GC on its own goroutine, if it runs at all.
+func stringToBytes(s string) []byte {
+	n := len(s)
+	if n == 0 {
+		return nil
+	}
+	// At least 16 bytes keeps the buffer out of the tiny allocator,
+	// otherwise the finalizer may never run.
+	buf := make([]byte, n, n+16)
+	copy(buf, s)
+	if n <= inplaceCheckMax {
+		runtime.SetFinalizer(&buf[0], func(p *byte) {
+			b := (*[inplaceCheckMax]byte)(unsafe.Pointer(p))[:n:n]
+			if string(b) != s {
+				inplaceMutated.Load().(func(string))(
+					"goutils: bytes returned by InplaceStringToSlice were modified, " +
+						"they may share memory with an immutable string; use []byte(s) to get a writable copy")
+			}
+		})
+	}
+	return buf[:n:n]
+}
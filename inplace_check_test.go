@@ -0,0 +1,41 @@
+//go:build goutils_debug
+// +build goutils_debug
+
+package goutils
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestInplaceStringToSliceMutation(t *testing.T) {
+	got := make(chan string, 2)
+	old := inplaceMutated.Load()
+	inplaceMutated.Store(func(msg string) {
+		got <- msg
+	})
+	defer inplaceMutated.Store(old)
+
+	func() {
+		InplaceStringToSlice(strings.Repeat("a", 4))
+		b := InplaceStringToSlice(strings.Repeat("b", 4))
+		b[0] = 'c'
+	}()
+	for i := 0; i < 50; i++ {
+		runtime.GC()
+		select {
+		case msg := <-got:
+			assert.True(t, strings.Contains(msg, "InplaceStringToSlice were modified"))
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+			assert.Equal(t, len(got), 0)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("mutation is not detected")
+}
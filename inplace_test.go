@@ -0,0 +1,20 @@
+//go:build !goutils_debug
+// +build !goutils_debug
+
+package goutils
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestInplaceStringToSliceShares(t *testing.T) {
+	s := string([]byte("shared bytes"))
+	b := InplaceStringToSlice(s)
+	data := (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+	assert.Equal(t, uintptr(unsafe.Pointer(&b[0])), data)
+	assert.Equal(t, cap(b), len(s))
+}
@@ -0,0 +1,12 @@
+//go:build go1.21
+// +build go1.21
+
+package goutils
+
+import "github.com/hanke0/goutils/slices"
+
+// Isin return true if the element is in the slice.
+// See slices.Contains for other element types.
+func Isin(s []string, elem string) bool {
+	return slices.Contains(s, elem)
+}
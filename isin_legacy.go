@@ -0,0 +1,14 @@
+//go:build !go1.21
+// +build !go1.21
+
+package goutils
+
+// Isin return true if the element is in the slice.
+func Isin(s []string, elem string) bool {
+	for _, ss := range s {
+		if ss == elem {
+			return true
+		}
+	}
+	return false
+}
@@ -3,69 +3,318 @@ package goutils
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
 	"sync"
 )
 
-var jsonEncoderPool sync.Pool
+// JSONOption configures ToJSON and its variants.
+type JSONOption interface {
+	apply(*jsonOption)
+}
+
+type jsonOption struct {
+	prefix     string
+	indent     string
+	escapeHTML bool
+	sortKeys   bool
+	newline    bool
+	floatFmt   byte
+	floatPrec  int
+	redact     *jsonRedact
+}
+
+type jsonOptionFunc func(*jsonOption)
+
+func (f jsonOptionFunc) apply(o *jsonOption) {
+	f(o)
+}
+
+// JSONIndent indents nested elements by indent, see json.Indent.
+func JSONIndent(indent string) JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		o.indent = indent
+	})
+}
+
+// JSONPrefix begins each line except the first one by prefix, see json.Indent.
+func JSONPrefix(prefix string) JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		o.prefix = prefix
+	})
+}
+
+// JSONEscapeHTML escapes <, > and & in strings like json.Marshal does.
+func JSONEscapeHTML() JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		o.escapeHTML = true
+	})
+}
+
+// JSONSortKeys sorts keys of all objects, including struct fields and json.RawMessage,
+// maps are always sorted by encoding/json.
+func JSONSortKeys() JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		o.sortKeys = true
+	})
+}
+
+// JSONNewline keeps the trailing newline that json.Encoder writes.
+func JSONNewline() JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		o.newline = true
+	})
+}
+
+// JSONFloatFormat formats non-integral numbers by strconv.FormatFloat with fmt and prec,
+// fmt must be one of 'e', 'E', 'f', 'g' or 'G'.
+// Note that encoding/json encodes integral floats like 1.0 as integers, they are kept as is.
+func JSONFloatFormat(fmt byte, prec int) JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		switch fmt {
+		case 'e', 'E', 'f', 'g', 'G':
+			o.floatFmt = fmt
+			o.floatPrec = prec
+		}
+	})
+}
+
+func newJSONOption(opts []JSONOption) jsonOption {
+	if len(opts) == 0 {
+		return jsonOption{}
+	}
+	var o jsonOption
+	for _, a := range opts {
+		a.apply(&o)
+	}
+	return o
+}
+
+// jsonEncoderPools are indexed by jsonencoderFlags, other options are applied
+// after encoding. Encoders with a prefix or an indent are not pooled.
+var jsonEncoderPools [2]sync.Pool
 
 type jsonencoder struct {
-	bs bytes.Buffer
-	j  json.Encoder
+	opt   jsonOption
+	bs    bytes.Buffer
+	tmp   bytes.Buffer
+	j     json.Encoder
+	flags int // -1 if not pooled
 }
 
-func (j *jsonencoder) setpretty() {
-	j.j.SetIndent("", "  ")
+// rewrite reports whether output is post-processed.
+func (o *jsonOption) rewrite() bool {
+	return o.sortKeys || o.floatFmt != 0
 }
 
 func (j *jsonencoder) reset() {
 	j.bs.Reset()
-	j.j.SetIndent("", "")
+	j.tmp.Reset()
+}
+
+// encode encodes o into the buffer with options, values are redacted by r if it's not nil.
+func (j *jsonencoder) encode(o interface{}, r *jsonRedact) error {
+	if r != nil {
+		v, err := r.value(reflect.ValueOf(o), 0)
+		if err != nil {
+			return err
+		}
+		o = v
+	}
+	if err := j.j.Encode(o); err != nil {
+		return err
+	}
+	j.bs.Truncate(j.bs.Len() - 1)
+	if j.opt.sortKeys {
+		if err := j.sortKeys(); err != nil {
+			return err
+		}
+	}
+	if j.opt.floatFmt != 0 {
+		j.formatFloats()
+	}
+	if j.opt.rewrite() && (j.opt.indent != "" || j.opt.prefix != "") {
+		j.tmp.Reset()
+		if err := json.Indent(&j.tmp, j.bs.Bytes(), j.opt.prefix, j.opt.indent); err != nil {
+			return err
+		}
+		j.swap()
+	}
+	if j.opt.newline {
+		j.bs.WriteByte('\n')
+	}
+	return nil
+}
+
+// swap moves the content of tmp into bs.
+func (j *jsonencoder) swap() {
+	j.bs.Reset()
+	j.bs.Write(j.tmp.Bytes())
+	j.tmp.Reset()
 }
 
-func getjsonencoder() *jsonencoder {
-	e, ok := jsonEncoderPool.Get().(*jsonencoder)
-	if !ok || e == nil {
-		e = new(jsonencoder)
+func (j *jsonencoder) sortKeys() error {
+	d := json.NewDecoder(bytes.NewReader(j.bs.Bytes()))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	j.tmp.Reset()
+	e := json.NewEncoder(&j.tmp)
+	e.SetEscapeHTML(j.opt.escapeHTML)
+	if err := e.Encode(v); err != nil {
+		return err
+	}
+	j.tmp.Truncate(j.tmp.Len() - 1)
+	j.swap()
+	return nil
+}
+
+func (j *jsonencoder) formatFloats() {
+	b := j.bs.Bytes()
+	j.tmp.Reset()
+	var num []byte
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == '"':
+			end := jsonStringEnd(b, i)
+			j.tmp.Write(b[i:end])
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(b) && isJSONNumberByte(b[end]) {
+				end++
+			}
+			s := b[i:end]
+			f, err := strconv.ParseFloat(string(s), 64)
+			if err == nil && bytes.ContainsAny(s, ".eE") {
+				num = strconv.AppendFloat(num[:0], f, j.opt.floatFmt, j.opt.floatPrec, 64)
+				j.tmp.Write(num)
+			} else {
+				j.tmp.Write(s)
+			}
+			i = end
+		default:
+			j.tmp.WriteByte(c)
+			i++
+		}
+	}
+	j.swap()
+}
+
+func isJSONNumberByte(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
+
+// jsonStringEnd returns the end offset of the JSON string starts at b[i].
+func jsonStringEnd(b []byte, i int) int {
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(b)
+}
+
+func jsonencoderFlags(o *jsonOption) int {
+	if o.prefix != "" || o.indent != "" {
+		return -1
+	}
+	flags := 0
+	if o.escapeHTML {
+		flags |= 1
+	}
+	return flags
+}
+
+func getjsonencoder(opt jsonOption) *jsonencoder {
+	opt.redact = nil
+	flags := jsonencoderFlags(&opt)
+	var e *jsonencoder
+	if flags >= 0 {
+		e, _ = jsonEncoderPools[flags].Get().(*jsonencoder)
+	}
+	if e == nil {
+		e = &jsonencoder{flags: flags}
 		e.j = *json.NewEncoder(&e.bs)
-		e.j.SetEscapeHTML(false)
-		return e
+		e.j.SetEscapeHTML(opt.escapeHTML)
+		if !opt.rewrite() {
+			e.j.SetIndent(opt.prefix, opt.indent)
+		}
 	}
+	e.opt = opt
 	return e
 }
 
 func putjsonencoder(e *jsonencoder) {
+	if e.flags < 0 {
+		return
+	}
 	e.reset()
-	jsonEncoderPool.Put(e)
+	jsonEncoderPools[e.flags].Put(e)
 }
 
 // ToJSON return the object json marshal not HTML escaped string.
 // An empty string returned if there is a marshal error.
-func ToJSON(o interface{}) string {
-	e := getjsonencoder()
-	defer putjsonencoder(e)
-	err := e.j.Encode(o)
+func ToJSON(o interface{}, opts ...JSONOption) string {
+	s, _ := MarshalString(o, opts...)
+	return s
+}
+
+// MustToJSON likes ToJSON but panics if there is a marshal error.
+func MustToJSON(o interface{}, opts ...JSONOption) string {
+	s, err := MarshalString(o, opts...)
 	if err != nil {
 		panic(err)
 	}
-	if e.bs.Len() > 1 {
-		e.bs.Truncate(e.bs.Len() - 1)
+	return s
+}
+
+// MarshalString return the object json marshal not HTML escaped string.
+func MarshalString(o interface{}, opts ...JSONOption) (string, error) {
+	opt := newJSONOption(opts)
+	e := getjsonencoder(opt)
+	defer putjsonencoder(e)
+	if err := e.encode(o, opt.redact); err != nil {
+		return "", err
 	}
-	return e.bs.String()
+	return e.bs.String(), nil
 }
 
-// ToPrettyJson like ToJSON with pretty output
-func ToPrettyJson(o interface{}) string { // nolint: revive
-	e := getjsonencoder()
+// AppendJSON appends the object json marshal not HTML escaped bytes to dst.
+// dst is returned unchanged if there is a marshal error.
+func AppendJSON(dst []byte, o interface{}, opts ...JSONOption) ([]byte, error) {
+	opt := newJSONOption(opts)
+	e := getjsonencoder(opt)
 	defer putjsonencoder(e)
-	e.setpretty()
-	err := e.j.Encode(o)
-	if err != nil {
-		panic(err)
+	if err := e.encode(o, opt.redact); err != nil {
+		return dst, err
 	}
-	if e.bs.Len() > 1 {
-		e.bs.Truncate(e.bs.Len() - 1)
+	return append(dst, e.bs.Bytes()...), nil
+}
+
+// WriteJSON writes the object json marshal not HTML escaped bytes to w, without trailing newline.
+// Nothing is written if there is a marshal error.
+func WriteJSON(w io.Writer, o interface{}, opts ...JSONOption) error {
+	opt := newJSONOption(opts)
+	e := getjsonencoder(opt)
+	defer putjsonencoder(e)
+	if err := e.encode(o, opt.redact); err != nil {
+		return err
 	}
-	return e.bs.String()
+	_, err := w.Write(e.bs.Bytes())
+	return err
+}
+
+// ToPrettyJson like ToJSON with pretty output
+func ToPrettyJson(o interface{}, opts ...JSONOption) string { // nolint: revive
+	return ToJSON(o, append([]JSONOption{JSONIndent("  ")}, opts...)...)
 }
 
 // ToPrettyJSON likes ToJSON with pretty output
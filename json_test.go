@@ -1,9 +1,12 @@
 package goutils
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"testing"
+
+	"github.com/hanke0/goutils/assert"
 )
 
 func TestToJSON(t *testing.T) {
@@ -44,6 +47,116 @@ func TestToPrettyJSON(t *testing.T) {
 	}
 }
 
+func TestJSONError(t *testing.T) {
+	bad := map[string]interface{}{"c": make(chan int)}
+	assert.Equal(t, ToJSON(bad), "")
+	assert.Equal(t, ToPrettyJSON(bad), "")
+	_, err := MarshalString(bad)
+	assert.NotNil(t, err)
+
+	dst, err := AppendJSON([]byte("x"), bad)
+	assert.NotNil(t, err)
+	assert.Equal(t, string(dst), "x")
+
+	var w bytes.Buffer
+	assert.NotNil(t, WriteJSON(&w, bad))
+	assert.Equal(t, w.Len(), 0)
+
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	MustToJSON(bad)
+}
+
+func TestAppendJSON(t *testing.T) {
+	dst, err := AppendJSON([]byte("x="), map[string]string{"a": "<b>"})
+	assert.Nil(t, err)
+	assert.Equal(t, string(dst), `x={"a":"<b>"}`)
+
+	s, err := MarshalString([]int{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, s, "[1,2]")
+	assert.Equal(t, MustToJSON(nil), "null")
+
+	var w bytes.Buffer
+	assert.Nil(t, WriteJSON(&w, "a&b"))
+	assert.Equal(t, w.String(), `"a&b"`)
+	assert.Equal(t, WriteJSON(errWriter{}, 1).Error(), "write fails")
+}
+
+func TestJSONOptions(t *testing.T) {
+	type item struct {
+		Z string          `json:"z"`
+		A float64         `json:"a"`
+		R json.RawMessage `json:"r"`
+	}
+	v := item{Z: "<&>", A: 1.5, R: json.RawMessage(`{"y":0.25,"x":"1.5"}`)}
+	cases := []struct {
+		name string
+		opts []JSONOption
+		want string
+	}{
+		{"default", nil, `{"z":"<&>","a":1.5,"r":{"y":0.25,"x":"1.5"}}`},
+		{"escape", []JSONOption{JSONEscapeHTML()}, `{"z":"\u003c\u0026\u003e","a":1.5,"r":{"y":0.25,"x":"1.5"}}`},
+		{"newline", []JSONOption{JSONNewline()}, `{"z":"<&>","a":1.5,"r":{"y":0.25,"x":"1.5"}}` + "\n"},
+		{"sort", []JSONOption{JSONSortKeys()}, `{"a":1.5,"r":{"x":"1.5","y":0.25},"z":"<&>"}`},
+		{"sort escape", []JSONOption{JSONSortKeys(), JSONEscapeHTML()}, `{"a":1.5,"r":{"x":"1.5","y":0.25},"z":"\u003c\u0026\u003e"}`},
+		{"float", []JSONOption{JSONFloatFormat('f', 2)}, `{"z":"<&>","a":1.50,"r":{"y":0.25,"x":"1.5"}}`},
+		{"float exp", []JSONOption{JSONFloatFormat('e', -1)}, `{"z":"<&>","a":1.5e+00,"r":{"y":2.5e-01,"x":"1.5"}}`},
+		{"bad float fmt", []JSONOption{JSONFloatFormat('x', 2)}, `{"z":"<&>","a":1.5,"r":{"y":0.25,"x":"1.5"}}`},
+		{"indent", []JSONOption{JSONIndent("\t"), JSONPrefix("//")},
+			"{\n//\t\"z\": \"<&>\",\n//\t\"a\": 1.5,\n//\t\"r\": {\n//\t\t\"y\": 0.25,\n//\t\t\"x\": \"1.5\"\n//\t}\n//}"},
+		{"indent sort", []JSONOption{JSONIndent(" "), JSONSortKeys(), JSONNewline()},
+			"{\n \"a\": 1.5,\n \"r\": {\n  \"x\": \"1.5\",\n  \"y\": 0.25\n },\n \"z\": \"<&>\"\n}\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				got, err := MarshalString(v, c.opts...)
+				assert.Nil(t, err)
+				assert.Equal(t, got, c.want)
+			}
+		})
+	}
+	assert.Equal(t, ToPrettyJSON([]int{1}, JSONIndent("\t")), "[\n\t1\n]")
+	assert.Equal(t, ToJSON(map[string]string{"s": `a\"1.5`}, JSONFloatFormat('f', 2)), `{"s":"a\\\"1.5"}`)
+}
+
+func TestJSONEncoderPool(t *testing.T) {
+	e := getjsonencoder(newJSONOption(nil))
+	assert.Equal(t, e.flags, 0)
+	putjsonencoder(e)
+	e = getjsonencoder(newJSONOption([]JSONOption{JSONRedact("x"), JSONSortKeys(), JSONNewline()}))
+	assert.Equal(t, e.flags, 0)
+	assert.True(t, e.opt.sortKeys && e.opt.newline)
+	putjsonencoder(e)
+	e = getjsonencoder(newJSONOption(nil))
+	assert.False(t, e.opt.sortKeys || e.opt.newline)
+	putjsonencoder(e)
+	e = getjsonencoder(newJSONOption([]JSONOption{JSONEscapeHTML()}))
+	assert.Equal(t, e.flags, 1)
+	putjsonencoder(e)
+
+	e = getjsonencoder(newJSONOption([]JSONOption{JSONIndent(" ")}))
+	assert.Equal(t, e.flags, -1)
+	putjsonencoder(e)
+	assert.Equal(t, ToJSON([]int{1}, JSONIndent(" ")), "[\n 1\n]")
+	assert.Equal(t, ToJSON("<", JSONEscapeHTML()), `"\u003c"`)
+	assert.Equal(t, ToJSON("<"), `"<"`)
+}
+
+func BenchmarkAppendJSON(b *testing.B) {
+	type sj struct {
+		I int    `json:"i"`
+		S string `json:"s"`
+	}
+	b.ReportAllocs()
+	var dst []byte
+	for i := 0; i < b.N; i++ {
+		dst, _ = AppendJSON(dst[:0], sj{i, "我&<>你"})
+	}
+}
+
 func BenchmarkToJSON(b *testing.B) {
 	type sj struct {
 		I int    `json:"i"`
@@ -0,0 +1,201 @@
+package goutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ToCanonicalJSON returns the RFC 8785 JSON Canonicalization Scheme (JCS) form of o,
+// equal data always produces identical bytes, so it's suitable for hashing and signing.
+//
+// o is marshaled by encoding/json first, use json.RawMessage to canonicalize raw JSON.
+// Numbers are converted to IEEE 754 double precision as JCS requires,
+// integers out of ±2^53 may lose precision.
+func ToCanonicalJSON(o interface{}) ([]byte, error) {
+	e := getjsonencoder(jsonOption{})
+	defer putjsonencoder(e)
+	if err := e.encode(o, nil); err != nil {
+		return nil, err
+	}
+	return CanonicalizeJSON(e.bs.Bytes())
+}
+
+// CanonicalizeJSON returns the RFC 8785 JSON Canonicalization Scheme (JCS) form of raw JSON.
+// Duplicated object keys are rejected.
+func CanonicalizeJSON(data []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	b, err := appendCanonicalJSON(nil, d)
+	if err != nil {
+		return nil, fmt.Errorf("jcs: %w", err)
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("jcs: invalid data after top-level value")
+	}
+	return b, nil
+}
+
+type canonicalMember struct {
+	key   string
+	value []byte
+}
+
+func appendCanonicalJSON(b []byte, d *json.Decoder) ([]byte, error) {
+	tok, err := d.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			b = append(b, '[')
+			for i := 0; d.More(); i++ {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				if b, err = appendCanonicalJSON(b, d); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := d.Token(); err != nil {
+				return nil, err
+			}
+			return append(b, ']'), nil
+		}
+		var members []canonicalMember
+		seen := map[string]bool{}
+		for d.More() {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := tok.(string)
+			if seen[key] {
+				return nil, fmt.Errorf("duplicated key %q", key)
+			}
+			seen[key] = true
+			v, err := appendCanonicalJSON(nil, d)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, canonicalMember{key, v})
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		sort.Slice(members, func(i, j int) bool {
+			return lessUTF16(members[i].key, members[j].key)
+		})
+		b = append(b, '{')
+		for i, m := range members {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendCanonicalString(b, m.key)
+			b = append(b, ':')
+			b = append(b, m.value...)
+		}
+		return append(b, '}'), nil
+	case string:
+		return appendCanonicalString(b, t), nil
+	case json.Number:
+		f, err := strconv.ParseFloat(string(t), 64)
+		if err != nil {
+			return nil, fmt.Errorf("number %s: %w", t, err)
+		}
+		return appendCanonicalNumber(b, f), nil
+	case bool:
+		return strconv.AppendBool(b, t), nil
+	case nil:
+		return append(b, "null"...), nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+// lessUTF16 compares strings by UTF-16 code units.
+func lessUTF16(a, b string) bool {
+	x, y := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return len(x) < len(y)
+}
+
+func appendCanonicalString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == '\b':
+			b = append(b, '\\', 'b')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\f':
+			b = append(b, '\\', 'f')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xf])
+		default:
+			b = append(b, string(r)...)
+		}
+	}
+	return append(b, '"')
+}
+
+// appendCanonicalNumber formats f like ECMAScript Number.prototype.toString.
+func appendCanonicalNumber(b []byte, f float64) []byte {
+	if f == 0 {
+		return append(b, '0')
+	}
+	if f < 0 {
+		b = append(b, '-')
+		f = -f
+	}
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	digits := strings.Replace(s[:i], ".", "", 1)
+	exp, _ := strconv.Atoi(s[i+1:])
+	k, n := len(digits), exp+1
+	switch {
+	case k <= n && n <= 21:
+		b = append(b, digits...)
+		b = append(b, strings.Repeat("0", n-k)...)
+	case 0 < n && n <= 21:
+		b = append(b, digits[:n]...)
+		b = append(b, '.')
+		b = append(b, digits[n:]...)
+	case -6 < n && n <= 0:
+		b = append(b, "0."...)
+		b = append(b, strings.Repeat("0", -n)...)
+		b = append(b, digits...)
+	default:
+		b = append(b, digits[0])
+		if k > 1 {
+			b = append(b, '.')
+			b = append(b, digits[1:]...)
+		}
+		b = append(b, 'e')
+		if n-1 >= 0 {
+			b = append(b, '+')
+		}
+		b = strconv.AppendInt(b, int64(n-1), 10)
+	}
+	return b
+}
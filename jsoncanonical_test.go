@@ -0,0 +1,61 @@
+package goutils
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestCanonicalizeJSON(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		// RFC 8785 section 3.2.2
+		{`{
+			"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			"string": "€$\u000F\u000aA'B\u0022\u005c\\\u0022\/",
+			"literals": [null, true, false]
+		}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],` +
+			`"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		// RFC 8785 section 3.2.3, sorted by UTF-16 code units
+		{`{"€": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh",
+			"1": "One", "😀": "Emoji: Grinning Face", "\u0080": "Control", "ö": "Latin Small Letter O With Diaeresis"}`,
+			`{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","ö":"Latin Small Letter O With Diaeresis",` +
+				`"€":"Euro Sign","😀":"Emoji: Grinning Face","` + "\ufb33" + `":"Hebrew Letter Dalet With Dagesh"}`},
+		{`[-0, 1e21, 1e20, 1e-6, 1e-7, 123e-20, -1.5, 9007199254740993]`,
+			`[0,1e+21,100000000000000000000,0.000001,1e-7,1.23e-18,-1.5,9007199254740992]`},
+		{`" <&>"`, "\" <&>\""},
+		{` { } `, `{}`},
+	}
+	for _, c := range cases {
+		got, err := CanonicalizeJSON([]byte(c.in))
+		assert.Nil(t, err)
+		assert.Equal(t, string(got), c.want)
+	}
+	for _, in := range []string{`{"a":1,"a":2}`, `[1e400]`, `{}{}`, `[1,`, ``} {
+		_, err := CanonicalizeJSON([]byte(in))
+		assert.NotNil(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "jcs: "))
+	}
+}
+
+func TestToCanonicalJSON(t *testing.T) {
+	type item struct {
+		Z string          `json:"z"`
+		A float64         `json:"a"`
+		R json.RawMessage `json:"r"`
+	}
+	got, err := ToCanonicalJSON(item{Z: "<>", A: 1e21, R: json.RawMessage(`{"y": 1.0, "x": [ ]}`)})
+	assert.Nil(t, err)
+	assert.Equal(t, string(got), `{"a":1e+21,"r":{"x":[],"y":1},"z":"<>"}`)
+
+	got, err = ToCanonicalJSON(json.RawMessage(`{"b": 2, "a": 1}`))
+	assert.Nil(t, err)
+	assert.Equal(t, string(got), `{"a":1,"b":2}`)
+
+	_, err = ToCanonicalJSON(math.NaN())
+	assert.NotNil(t, err)
+}
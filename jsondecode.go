@@ -0,0 +1,498 @@
+package goutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrJSONTooDeep is returned if a document is nested deeper than JSONDecodeMaxDepth.
+	ErrJSONTooDeep = errors.New("exceeded max depth")
+	// ErrJSONTooLarge is returned if a document is larger than JSONDecodeMaxSize.
+	ErrJSONTooLarge = errors.New("exceeded max size")
+)
+
+// JSONDecodeError reports where decoding fails.
+type JSONDecodeError struct {
+	Path   string // JSONPath of the failing value, e.g. $.items[2].name
+	Offset int64  // byte offset of the failing value
+	Err    error
+}
+
+func (e *JSONDecodeError) Error() string {
+	return fmt.Sprintf("fromjson: %s (offset %d): %v", e.Path, e.Offset, e.Err)
+}
+
+func (e *JSONDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// JSONDecodeOption configures FromJSON and its variants.
+type JSONDecodeOption interface {
+	apply(*jsonDecodeOption)
+}
+
+type jsonDecodeOption struct {
+	disallowUnknown bool
+	useNumber       bool
+	maxDepth        int
+	maxSize         int64
+}
+
+type jsonDecodeOptionFunc func(*jsonDecodeOption)
+
+func (f jsonDecodeOptionFunc) apply(o *jsonDecodeOption) {
+	f(o)
+}
+
+// JSONDecodeDisallowUnknownFields rejects object keys that do not match any struct field,
+// see json.Decoder.DisallowUnknownFields.
+func JSONDecodeDisallowUnknownFields() JSONDecodeOption {
+	return jsonDecodeOptionFunc(func(o *jsonDecodeOption) {
+		o.disallowUnknown = true
+	})
+}
+
+// JSONDecodeUseNumber decodes numbers into interface{} as json.Number, see json.Decoder.UseNumber.
+func JSONDecodeUseNumber() JSONDecodeOption {
+	return jsonDecodeOptionFunc(func(o *jsonDecodeOption) {
+		o.useNumber = true
+	})
+}
+
+// JSONDecodeMaxDepth limits the nesting depth of objects and arrays, zero means no limit.
+func JSONDecodeMaxDepth(n int) JSONDecodeOption {
+	return jsonDecodeOptionFunc(func(o *jsonDecodeOption) {
+		o.maxDepth = n
+	})
+}
+
+// JSONDecodeMaxSize limits the size of the document in bytes, zero means no limit.
+func JSONDecodeMaxSize(n int64) JSONDecodeOption {
+	return jsonDecodeOptionFunc(func(o *jsonDecodeOption) {
+		o.maxSize = n
+	})
+}
+
+// jsonDecoderPools are indexed by jsondecoderFlags, since options of json.Decoder
+// could not be turned off.
+var jsonDecoderPools [4]sync.Pool
+
+// jsondecoder holds buffers and the json.Decoder reused between decoding.
+type jsondecoder struct {
+	buf   bytes.Buffer
+	rd    bytes.Reader
+	dec   *json.Decoder
+	flags int
+}
+
+func jsondecoderFlags(o *jsonDecodeOption) int {
+	flags := 0
+	if o.useNumber {
+		flags |= 1
+	}
+	if o.disallowUnknown {
+		flags |= 2
+	}
+	return flags
+}
+
+func getjsondecoder(o *jsonDecodeOption) *jsondecoder {
+	flags := jsondecoderFlags(o)
+	d, ok := jsonDecoderPools[flags].Get().(*jsondecoder)
+	if !ok || d == nil {
+		d = &jsondecoder{flags: flags}
+	}
+	if d.dec == nil {
+		d.dec = json.NewDecoder(&d.rd)
+		if o.useNumber {
+			d.dec.UseNumber()
+		}
+		if o.disallowUnknown {
+			d.dec.DisallowUnknownFields()
+		}
+	}
+	return d
+}
+
+func putjsondecoder(d *jsondecoder) {
+	d.buf.Reset()
+	d.rd.Reset(nil)
+	jsonDecoderPools[d.flags].Put(d)
+}
+
+func newJSONDecodeOption(opts []JSONDecodeOption) jsonDecodeOption {
+	var o jsonDecodeOption
+	for _, a := range opts {
+		a.apply(&o)
+	}
+	return o
+}
+
+// FromJSON decodes the JSON document s into v, it's the counterpart of ToJSON.
+// Errors of bad documents are *JSONDecodeError.
+func FromJSON(s string, v interface{}, opts ...JSONDecodeOption) error {
+	o := newJSONDecodeOption(opts)
+	d := getjsondecoder(&o)
+	defer putjsondecoder(d)
+	d.buf.WriteString(s)
+	return d.decode(d.buf.Bytes(), v, &o)
+}
+
+// FromJSONBytes likes FromJSON but decodes from bytes.
+func FromJSONBytes(data []byte, v interface{}, opts ...JSONDecodeOption) error {
+	o := newJSONDecodeOption(opts)
+	d := getjsondecoder(&o)
+	defer putjsondecoder(d)
+	return d.decode(data, v, &o)
+}
+
+// FromJSONReader likes FromJSON but decodes a single document read from r until EOF.
+// Errors of r are returned as is.
+func FromJSONReader(r io.Reader, v interface{}, opts ...JSONDecodeOption) error {
+	o := newJSONDecodeOption(opts)
+	d := getjsondecoder(&o)
+	defer putjsondecoder(d)
+	if o.maxSize > 0 {
+		r = io.LimitReader(r, o.maxSize+1)
+	}
+	if _, err := d.buf.ReadFrom(r); err != nil {
+		return err
+	}
+	return d.decode(d.buf.Bytes(), v, &o)
+}
+
+func (d *jsondecoder) decode(data []byte, v interface{}, o *jsonDecodeOption) error {
+	if o.maxSize > 0 && int64(len(data)) > o.maxSize {
+		return newJSONDecodeError(data[:o.maxSize], int(o.maxSize), ErrJSONTooLarge)
+	}
+	if o.maxDepth > 0 {
+		if path, offset, found := locateJSON(data, -1, o.maxDepth); found {
+			return &JSONDecodeError{Path: path, Offset: int64(offset), Err: ErrJSONTooDeep}
+		}
+	}
+	// The decoder is reused only if it reads the whole input, so trailing spaces are
+	// not fed to it.
+	input := bytes.TrimRight(data, " \t\r\n")
+	d.rd.Reset(input)
+	if err := d.dec.Decode(v); err != nil {
+		// The decoder keeps read errors, and offsets of its syntax errors
+		// count all documents it has read.
+		d.dec = nil
+		return jsonDecodeError(data, v, err)
+	}
+	if buffered, _ := io.Copy(ioutil.Discard, d.dec.Buffered()); buffered > 0 || d.rd.Len() > 0 {
+		d.dec = nil
+		end := len(input) - d.rd.Len() - int(buffered)
+		for end < len(data) && isJSONSpace(data[end]) {
+			end++
+		}
+		return &JSONDecodeError{Path: "$", Offset: int64(end), Err: errors.New("invalid data after top-level value")}
+	}
+	return nil
+}
+
+// jsonDecodeError adds the location to errors of encoding/json.
+func jsonDecodeError(data []byte, v interface{}, err error) error {
+	var (
+		se *json.SyntaxError
+		te *json.UnmarshalTypeError
+	)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return newJSONDecodeError(data, len(data), io.ErrUnexpectedEOF)
+	case errors.As(err, &se):
+		// Checks again for the offset in data.
+		var raw json.RawMessage
+		if verr := json.Unmarshal(data, &raw); errors.As(verr, &se) {
+			err = verr
+		}
+		return newJSONDecodeError(data, int(se.Offset)-1, err)
+	case errors.As(err, &te):
+		return newJSONDecodeError(data, int(te.Offset)-1, err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		key, uerr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		if uerr == nil {
+			l := jsonFieldLocator{data: data, key: key}
+			if found, _ := l.walk(0, reflect.TypeOf(v)); found {
+				return &JSONDecodeError{Path: l.path, Offset: int64(l.offset), Err: err}
+			}
+		}
+	}
+	return err
+}
+
+func newJSONDecodeError(data []byte, offset int, err error) error {
+	if offset < 0 {
+		offset = 0
+	}
+	path, offset, _ := locateJSON(data, offset, 0)
+	return &JSONDecodeError{Path: path, Offset: int64(offset), Err: err}
+}
+
+type jsonFrame struct {
+	array  bool
+	index  int
+	key    string
+	hasKey bool
+}
+
+func formatJSONFrames(frames []jsonFrame) string {
+	var b strings.Builder
+	b.WriteByte('$')
+	for _, f := range frames {
+		switch {
+		case !f.array && !f.hasKey:
+		case f.array:
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(f.index))
+			b.WriteByte(']')
+		case isJSONPathName(f.key):
+			b.WriteByte('.')
+			b.WriteString(f.key)
+		default:
+			b.WriteString("['")
+			b.WriteString(strings.Replace(strings.Replace(f.key, `\`, `\\`, -1), `'`, `\'`, -1))
+			b.WriteString("']")
+		}
+	}
+	return b.String()
+}
+
+func isJSONPathName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// locateJSON scans data, it returns the path and offset of
+//   1. the first object or array nested deeper than maxDepth if maxDepth > 0, or
+//   2. the innermost value contains the byte at offset at if at >= 0.
+// The scan is lenient and never fails on bad syntax.
+func locateJSON(data []byte, at int, maxDepth int) (path string, offset int, found bool) {
+	var stack []jsonFrame
+	expectKey := false
+	for i := 0; i < len(data); {
+		c := data[i]
+		end := i + 1
+		switch {
+		case c == '"':
+			end = jsonStringEnd(data, i)
+		case !isJSONSpace(c) && strings.IndexByte("{}[],:", c) < 0:
+			for end < len(data) && !isJSONSpace(data[end]) && strings.IndexByte("{}[],:\"", data[end]) < 0 {
+				end++
+			}
+		}
+		isKey := c == '"' && expectKey && len(stack) > 0 && !stack[len(stack)-1].array
+		if isKey {
+			var k string
+			if json.Unmarshal(data[i:end], &k) == nil {
+				stack[len(stack)-1].key = k
+				stack[len(stack)-1].hasKey = true
+			}
+			expectKey = false
+		}
+		if at >= 0 && at < end {
+			if (c == '}' || c == ']') && len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			return formatJSONFrames(stack), i, true
+		}
+		switch c {
+		case '{', '[':
+			if maxDepth > 0 && len(stack) >= maxDepth {
+				return formatJSONFrames(stack), i, true
+			}
+			stack = append(stack, jsonFrame{array: c == '['})
+			expectKey = c == '{'
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			expectKey = false
+		case ',':
+			if len(stack) > 0 {
+				top := &stack[len(stack)-1]
+				if top.array {
+					top.index++
+				} else {
+					top.hasKey = false
+					expectKey = true
+				}
+			}
+		}
+		i = end
+	}
+	if at >= 0 {
+		return formatJSONFrames(stack), len(data), true
+	}
+	return "$", 0, false
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// jsonFieldLocator finds the object key rejected by json.Decoder.DisallowUnknownFields,
+// it walks a valid document along with the type decoded into.
+type jsonFieldLocator struct {
+	data   []byte
+	key    string
+	frames []jsonFrame
+	path   string
+	offset int
+}
+
+func (l *jsonFieldLocator) skipSpace(i int) int {
+	for i < len(l.data) && isJSONSpace(l.data[i]) {
+		i++
+	}
+	return i
+}
+
+// skip returns the end of the value starts at i.
+func (l *jsonFieldLocator) skip(i int) int {
+	depth := 0
+	for i < len(l.data) {
+		c := l.data[i]
+		switch {
+		case c == '"':
+			i = jsonStringEnd(l.data, i)
+			if depth == 0 {
+				return i
+			}
+			continue
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth <= 0 {
+				return i + 1
+			}
+		case depth == 0 && !isJSONSpace(c):
+			for i < len(l.data) && !isJSONSpace(l.data[i]) && strings.IndexByte(",:]}", l.data[i]) < 0 {
+				i++
+			}
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// walk walks the value starts at i and decoded into type t, it returns true if the key is found
+// and the index of the end of the value.
+func (l *jsonFieldLocator) walk(i int, t reflect.Type) (found bool, end int) {
+	i = l.skipSpace(i)
+	for {
+		if t.Implements(jsonUnmarshalerType) ||
+			(t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(jsonUnmarshalerType)) {
+			return false, l.skip(i)
+		}
+		if t.Kind() != reflect.Ptr {
+			break
+		}
+		t = t.Elem()
+	}
+	if i >= len(l.data) {
+		return false, i
+	}
+	switch {
+	case l.data[i] == '{' && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map):
+		return l.walkObject(i, t)
+	case l.data[i] == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		l.frames = append(l.frames, jsonFrame{array: true})
+		defer func() { l.frames = l.frames[:len(l.frames)-1] }()
+		i++
+		for {
+			i = l.skipSpace(i)
+			if i >= len(l.data) || l.data[i] == ']' {
+				return false, i + 1
+			}
+			if l.data[i] == ',' {
+				l.frames[len(l.frames)-1].index++
+				i++
+				continue
+			}
+			if found, i = l.walk(i, t.Elem()); found {
+				return true, i
+			}
+		}
+	default:
+		return false, l.skip(i)
+	}
+}
+
+func (l *jsonFieldLocator) walkObject(i int, t reflect.Type) (found bool, end int) {
+	var fields []jsonField
+	if t.Kind() == reflect.Struct {
+		fields = jsonFieldsOf(t)
+	}
+	l.frames = append(l.frames, jsonFrame{})
+	defer func() { l.frames = l.frames[:len(l.frames)-1] }()
+	i++
+	for {
+		i = l.skipSpace(i)
+		if i >= len(l.data) || l.data[i] == '}' {
+			return false, i + 1
+		}
+		if l.data[i] == ',' {
+			i++
+			continue
+		}
+		start := i
+		i = jsonStringEnd(l.data, i)
+		top := &l.frames[len(l.frames)-1]
+		top.key, top.hasKey = "", true
+		_ = json.Unmarshal(l.data[start:i], &top.key)
+		i = l.skipSpace(i) + 1 // skips colon
+		vt := t
+		if t.Kind() == reflect.Map {
+			vt = t.Elem()
+		} else if f, ok := jsonFieldByName(fields, top.key); ok {
+			vt = t.FieldByIndex(f.index).Type
+		} else {
+			if top.key == l.key {
+				l.path, l.offset = formatJSONFrames(l.frames), start
+				return true, i
+			}
+			i = l.skip(l.skipSpace(i))
+			continue
+		}
+		if found, i = l.walk(i, vt); found {
+			return true, i
+		}
+	}
+}
+
+// jsonFieldByName matches key to fields like encoding/json, an exact match is preferred
+// to a case-insensitive match.
+func jsonFieldByName(fields []jsonField, key string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
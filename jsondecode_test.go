@@ -0,0 +1,145 @@
+package goutils
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+type decodeItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type decodeDoc struct {
+	ID    int          `json:"id"`
+	Items []decodeItem `json:"items"`
+	Meta  interface{}  `json:"meta"`
+}
+
+func TestFromJSON(t *testing.T) {
+	var d decodeDoc
+	assert.Nil(t, FromJSON(`{"id":1,"items":[{"name":"a","count":2}],"meta":{"n":1.50}}`, &d, JSONDecodeUseNumber()))
+	assert.Equal(t, d.ID, 1)
+	assert.Equal(t, d.Items, []decodeItem{{"a", 2}})
+	assert.Equal(t, d.Meta, map[string]interface{}{"n": json.Number("1.50")})
+
+	var n int
+	assert.Nil(t, FromJSONBytes([]byte(" 42 \n"), &n))
+	assert.Equal(t, n, 42)
+	assert.Nil(t, FromJSONReader(strings.NewReader(`7`), &n, JSONDecodeMaxSize(1)))
+	assert.Equal(t, n, 7)
+	assert.Equal(t, FromJSONReader(errReader{}, &n).Error(), "read fails")
+}
+
+func TestFromJSONError(t *testing.T) {
+	doc := `{"id":1,"items":[{"name":"a"},{"name":"b","count":"x"}],"meta":[[[1]]]}`
+	cases := []struct {
+		name string
+		s    string
+		opts []JSONDecodeOption
+		path string
+		off  int64
+		err  error
+	}{
+		{"type", doc, nil, "$.items[1].count", 50, nil},
+		{"depth", doc, []JSONDecodeOption{JSONDecodeMaxDepth(3)}, "$.meta[0][0]", 65, ErrJSONTooDeep},
+		{"size", doc, []JSONDecodeOption{JSONDecodeMaxSize(20)}, "$.items[0]", 20, ErrJSONTooLarge},
+		{"unknown", `{"id":1,"items":[{"name":"a","size":1}]}`, []JSONDecodeOption{JSONDecodeDisallowUnknownFields()},
+			"$.items[0].size", 29, nil},
+		{"syntax", `{"id":1,"items":[{"name" "a"}]}`, nil, "$.items[0].name", 25, nil},
+		{"object type", `{"id":{"a":1}}`, nil, "$.id", 6, nil},
+		{"eof", `{"id":1,"items":[`, nil, "$.items[0]", 17, io.ErrUnexpectedEOF},
+		{"trailing", `{"id":1} {}`, nil, "$", 9, nil},
+		{"quoted key", `{"items":[{"a.b":1}]}`, []JSONDecodeOption{JSONDecodeDisallowUnknownFields()},
+			"$.items[0]['a.b']", 11, nil},
+		{"unknown after map", `{"meta":{"size":1},"items":[{"NAME":"a"},{"Count":1,"size":2}]}`,
+			[]JSONDecodeOption{JSONDecodeDisallowUnknownFields()}, "$.items[1].size", 52, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var d decodeDoc
+			err := FromJSON(c.s, &d, c.opts...)
+			var de *JSONDecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			assert.Equal(t, de.Path, c.path)
+			assert.Equal(t, de.Offset, c.off)
+			if c.err != nil {
+				assert.True(t, errors.Is(err, c.err))
+			}
+		})
+	}
+
+	var d decodeDoc
+	err := FromJSONReader(strings.NewReader(doc), &d, JSONDecodeMaxSize(20))
+	assert.True(t, errors.Is(err, ErrJSONTooLarge))
+	assert.Equal(t, err.Error(), "fromjson: $.items[0] (offset 20): exceeded max size")
+}
+
+type decodeRaw struct{ raw string }
+
+func (d *decodeRaw) UnmarshalJSON(b []byte) error {
+	d.raw = string(b)
+	return nil
+}
+
+func TestFromJSONUnknownField(t *testing.T) {
+	type inner struct {
+		decodeItem
+		Extra decodeRaw `json:"extra"`
+	}
+	type outer struct {
+		Meta  map[string]interface{} `json:"meta"`
+		List  [][]*inner             `json:"list"`
+		Inner *inner                 `json:"inner"`
+	}
+	s := `{"meta":{"extra":1},"list":[[{"name":"x","extra":{"extra":1}}]],"inner":{"name":"a","extra":2,"Extra":3,"x":{"extra":4}}}`
+	var v outer
+	err := FromJSON(s, &v, JSONDecodeDisallowUnknownFields())
+	var de *JSONDecodeError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, de.Path, "$.inner.x")
+	assert.Equal(t, de.Offset, int64(104))
+	assert.Equal(t, v.Inner.Extra.raw, "3")
+	assert.Equal(t, v.List[0][0].Extra.raw, `{"extra":1}`)
+}
+
+func TestFromJSONReuse(t *testing.T) {
+	docs := []struct {
+		s    string
+		path string
+		off  int64
+	}{
+		{`{"id":1}`, "", 0},
+		{" \n{\"id\":2}\n", "", 0},
+		{`{"id":3,}`, "$", 8},
+		{`7`, "", 0},
+		{`{"id":[1]}`, "$.id", 6},
+		{`{"id":4} 5`, "$", 9},
+		{`{"items":[{"name" 1}]}`, "$.items[0].name", 18},
+		{`{"id":5}`, "", 0},
+	}
+	for i := 0; i < 10; i++ {
+		for _, d := range docs {
+			var v interface{} = &decodeDoc{}
+			if d.s == "7" {
+				v = new(int)
+			}
+			err := FromJSON(d.s, v)
+			if d.path == "" {
+				assert.Nil(t, err)
+				continue
+			}
+			var de *JSONDecodeError
+			assert.True(t, errors.As(err, &de))
+			assert.Equal(t, de.Path, d.path)
+			assert.Equal(t, de.Offset, d.off)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package goutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrJSONLLineTooLong is returned if a line of JSON Lines exceeds the max line size.
+var ErrJSONLLineTooLong = errors.New("line too long")
+
+const defaultJSONLMaxLineSize = 1 << 20
+
+// JSONLEncoder writes JSON Lines, one compact record per line.
+type JSONLEncoder struct {
+	w   io.Writer
+	opt jsonOption
+}
+
+// NewJSONLEncoder returns a JSONLEncoder writes to w.
+// JSONIndent, JSONPrefix and JSONNewline are ignored, records are always compact.
+func NewJSONLEncoder(w io.Writer, opts ...JSONOption) *JSONLEncoder {
+	opt := newJSONOption(opts)
+	opt.indent = ""
+	opt.prefix = ""
+	opt.newline = true
+	return &JSONLEncoder{w: w, opt: opt}
+}
+
+// Encode writes v and a newline to the writer with a single Write call.
+// Nothing is written if there is a marshal error.
+func (e *JSONLEncoder) Encode(v interface{}) error {
+	j := getjsonencoder(e.opt)
+	defer putjsonencoder(j)
+	if err := j.encode(v, e.opt.redact); err != nil {
+		return err
+	}
+	_, err := e.w.Write(j.bs.Bytes())
+	return err
+}
+
+// JSONLError reports a bad line of JSON Lines.
+type JSONLError struct {
+	Line   int   // line number starts from 1
+	Offset int64 // byte offset of the line start
+	Err    error
+}
+
+func (e *JSONLError) Error() string {
+	return fmt.Sprintf("jsonl: line %d (offset %d): %v", e.Line, e.Offset, e.Err)
+}
+
+func (e *JSONLError) Unwrap() error {
+	return e.Err
+}
+
+// JSONLOption configures JSONLDecoder.
+type JSONLOption interface {
+	apply(*jsonlOption)
+}
+
+type jsonlOption struct {
+	maxLineSize     int
+	skip            bool
+	useNumber       bool
+	disallowUnknown bool
+}
+
+type jsonlOptionFunc func(*jsonlOption)
+
+func (f jsonlOptionFunc) apply(o *jsonlOption) {
+	f(o)
+}
+
+// JSONLMaxLineSize limits the size of a line excluding the line ending, default is 1 MiB.
+// Zero or negative means no limit.
+func JSONLMaxLineSize(n int) JSONLOption {
+	return jsonlOptionFunc(func(o *jsonlOption) {
+		o.maxLineSize = n
+	})
+}
+
+// JSONLSkipMalformed skips bad lines instead of returning errors, errors are collected
+// and could be got by JSONLDecoder.Errors.
+// The value passed to Decode may be partially filled by skipped lines.
+func JSONLSkipMalformed() JSONLOption {
+	return jsonlOptionFunc(func(o *jsonlOption) {
+		o.skip = true
+	})
+}
+
+// JSONLUseNumber decodes numbers into interface{} as json.Number, see json.Decoder.UseNumber.
+func JSONLUseNumber() JSONLOption {
+	return jsonlOptionFunc(func(o *jsonlOption) {
+		o.useNumber = true
+	})
+}
+
+// JSONLDisallowUnknownFields rejects unknown object keys, see json.Decoder.DisallowUnknownFields.
+func JSONLDisallowUnknownFields() JSONLOption {
+	return jsonlOptionFunc(func(o *jsonlOption) {
+		o.disallowUnknown = true
+	})
+}
+
+// JSONLDecoder reads JSON Lines record by record. Blank lines are ignored.
+type JSONLDecoder struct {
+	r      *bufio.Reader
+	opt    jsonlOption
+	line   int
+	start  int64 // offset of the last read line
+	offset int64
+	buf    []byte
+	errs   []error
+}
+
+// NewJSONLDecoder returns a JSONLDecoder reads from r.
+func NewJSONLDecoder(r io.Reader, opts ...JSONLOption) *JSONLDecoder {
+	d := &JSONLDecoder{r: bufio.NewReader(r), opt: jsonlOption{maxLineSize: defaultJSONLMaxLineSize}}
+	for _, a := range opts {
+		a.apply(&d.opt)
+	}
+	return d
+}
+
+// Decode decodes the next record into v, it returns io.EOF if there are no more records.
+// Bad lines are reported by *JSONLError unless JSONLSkipMalformed is given.
+// Errors of the underlying reader are returned as is.
+func (d *JSONLDecoder) Decode(v interface{}) error {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			if !d.skipError(err) {
+				return err
+			}
+			continue
+		}
+		if line == nil {
+			return io.EOF
+		}
+		if err := d.decode(line, v); err != nil {
+			err = &JSONLError{Line: d.line, Offset: d.start, Err: err}
+			if !d.skipError(err) {
+				return err
+			}
+			continue
+		}
+		return nil
+	}
+}
+
+// Errors returns errors of skipped lines.
+func (d *JSONLDecoder) Errors() []error {
+	return d.errs
+}
+
+// Line returns the line number of the last read line.
+func (d *JSONLDecoder) Line() int {
+	return d.line
+}
+
+func (d *JSONLDecoder) skipError(err error) bool {
+	var le *JSONLError
+	if !d.opt.skip || !errors.As(err, &le) {
+		return false
+	}
+	d.errs = append(d.errs, err)
+	return true
+}
+
+func (d *JSONLDecoder) decode(line []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	if d.opt.useNumber {
+		dec.UseNumber()
+	}
+	if d.opt.disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after value")
+	}
+	return nil
+}
+
+// readLine reads the next non-blank line without line ending, it returns nil at the end.
+func (d *JSONLDecoder) readLine() ([]byte, error) {
+	for {
+		d.buf = d.buf[:0]
+		d.start = d.offset
+		tooLong := false
+		for {
+			frag, err := d.r.ReadSlice('\n')
+			d.offset += int64(len(frag))
+			if !tooLong {
+				d.buf = append(d.buf, frag...)
+				if n := d.opt.maxLineSize; n > 0 && len(bytes.TrimRight(d.buf, "\r\n")) > n {
+					tooLong = true
+					d.buf = d.buf[:0]
+				}
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if err == io.EOF && d.offset == d.start {
+				return nil, nil
+			}
+			break
+		}
+		d.line++
+		if tooLong {
+			return nil, &JSONLError{Line: d.line, Offset: d.start, Err: ErrJSONLLineTooLong}
+		}
+		line := bytes.TrimRight(d.buf, "\r\n")
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+	}
+}
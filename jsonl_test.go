@@ -0,0 +1,94 @@
+package goutils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestJSONLEncoder(t *testing.T) {
+	var w bytes.Buffer
+	e := NewJSONLEncoder(&w, JSONIndent("  "), JSONSortKeys())
+	assert.Nil(t, e.Encode(map[string]interface{}{"b": "<1>", "a": []int{1, 2}}))
+	assert.Nil(t, e.Encode("x\ny"))
+	assert.NotNil(t, e.Encode(make(chan int)))
+	assert.Equal(t, w.String(), `{"a":[1,2],"b":"<1>"}`+"\n"+`"x\ny"`+"\n")
+	assert.Equal(t, NewJSONLEncoder(errWriter{}).Encode(1).Error(), "write fails")
+}
+
+type jsonlRecord struct {
+	A int `json:"a"`
+}
+
+func TestJSONLDecoder(t *testing.T) {
+	input := "{\"a\":1}\r\n\n  \n{\"a\":2}\n{\"a\":\n" + `{"a":"x"}` + "\n" + `{"a":3}{}` + "\n" + `{"a":4}`
+	d := NewJSONLDecoder(strings.NewReader(input))
+	var r jsonlRecord
+	assert.Nil(t, d.Decode(&r))
+	assert.Equal(t, r.A, 1)
+	assert.Nil(t, d.Decode(&r))
+	assert.Equal(t, r.A, 2)
+	assert.Equal(t, d.Line(), 4)
+
+	err := d.Decode(&r)
+	var le *JSONLError
+	assert.True(t, errors.As(err, &le))
+	assert.Equal(t, le.Line, 5)
+	assert.Equal(t, le.Offset, int64(21))
+	assert.Equal(t, err.Error(), "jsonl: line 5 (offset 21): unexpected EOF")
+
+	err = d.Decode(&r)
+	assert.True(t, strings.HasPrefix(err.Error(), "jsonl: line 6 (offset 27): json: cannot unmarshal string"))
+	err = d.Decode(&r)
+	assert.Equal(t, err.Error(), "jsonl: line 7 (offset 37): invalid data after value")
+	assert.Nil(t, d.Decode(&r))
+	assert.Equal(t, r.A, 4)
+	assert.Equal(t, d.Decode(&r), io.EOF)
+	assert.Equal(t, d.Decode(&r), io.EOF)
+}
+
+func TestJSONLDecoderSkip(t *testing.T) {
+	long := `{"a":1,"b":"` + strings.Repeat("x", 100) + `"}`
+	input := `{"a":1}` + "\n" + long + "\n" + `{"a":2,"b":1}` + "\n" + `{"a":3}` + "\n" + long
+	d := NewJSONLDecoder(strings.NewReader(input), JSONLMaxLineSize(20), JSONLSkipMalformed(),
+		JSONLDisallowUnknownFields())
+	var got []int
+	for {
+		var r jsonlRecord
+		err := d.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		got = append(got, r.A)
+	}
+	assert.Equal(t, got, []int{1, 3})
+	errs := d.Errors()
+	assert.Equal(t, len(errs), 3)
+	assert.True(t, errors.Is(errs[0], ErrJSONLLineTooLong))
+	assert.Equal(t, errs[0].(*JSONLError).Line, 2)
+	assert.True(t, strings.Contains(errs[1].Error(), `line 3 (offset 123): json: unknown field "b"`))
+	assert.Equal(t, errs[2].(*JSONLError).Line, 5)
+
+	d = NewJSONLDecoder(strings.NewReader(strings.Repeat("1", 5000)), JSONLMaxLineSize(0), JSONLUseNumber())
+	var v interface{}
+	assert.Nil(t, d.Decode(&v))
+	assert.Equal(t, len(v.(interface{ String() string }).String()), 5000)
+}
+
+func TestJSONLDecoderReadError(t *testing.T) {
+	d := NewJSONLDecoder(io.MultiReader(strings.NewReader("1\n"), errReader{}), JSONLSkipMalformed())
+	var v int
+	assert.Nil(t, d.Decode(&v))
+	assert.Equal(t, d.Decode(&v).Error(), "read fails")
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read fails")
+}
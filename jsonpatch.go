@@ -0,0 +1,368 @@
+package goutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// ErrJSONTestFailed is returned if a test operation of JSON Patch fails.
+var ErrJSONTestFailed = errors.New("test failed")
+
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	From  string          `json:"from,omitempty"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch applies the RFC 6902 JSON Patch to doc and returns the patched document.
+// Operations are applied atomically, doc is never modified.
+// Object keys of the result are sorted.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("jsonpatch: bad patch: %w", err)
+	}
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		v, err = applyJSONPatchOp(v, op)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpatch: op %d (%s %q): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return AppendJSON(nil, v)
+}
+
+func applyJSONPatchOp(doc interface{}, op jsonPatchOp) (interface{}, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		if value, err = decodeJSON(op.Value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if isJSONPointerPrefix(from, path) && len(from) < len(path) {
+				return nil, errors.New("cannot move a value into its child")
+			}
+			doc, value, err = jsonRemove(doc, from)
+		} else {
+			value, err = jsonPointerGet(doc, from)
+			value = copyJSON(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("from %q: %w", op.From, err)
+		}
+	}
+	switch op.Op {
+	case "add", "move", "copy":
+		return jsonAdd(doc, path, value)
+	case "remove":
+		doc, _, err = jsonRemove(doc, path)
+		return doc, err
+	case "replace":
+		if doc, _, err = jsonRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, path, value)
+	case "test":
+		old, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(old, value) {
+			return nil, ErrJSONTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+func isJSONPointerPrefix(prefix, tokens []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonAdd adds value at tokens and returns the new document.
+func jsonAdd(v interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	t := tokens[0]
+	switch c := v.(type) {
+	case map[string]interface{}:
+		if len(tokens) == 1 {
+			c[t] = value
+			return c, nil
+		}
+		x, ok := c[t]
+		if !ok {
+			return nil, ErrJSONNotFound
+		}
+		x, err := jsonAdd(x, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		c[t] = x
+		return c, nil
+	case []interface{}:
+		if len(tokens) == 1 {
+			i := len(c)
+			if t != "-" {
+				var err error
+				if i, err = jsonIndex(t, len(c)); err != nil {
+					return nil, err
+				}
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		i, err := jsonIndex(t, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		if c[i], err = jsonAdd(c[i], tokens[1:], value); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, ErrJSONNotFound
+}
+
+// jsonRemove removes the value at tokens and returns the new document and the removed value.
+func jsonRemove(v interface{}, tokens []string) (doc, removed interface{}, err error) {
+	if len(tokens) == 0 {
+		return nil, v, nil
+	}
+	t := tokens[0]
+	switch c := v.(type) {
+	case map[string]interface{}:
+		x, ok := c[t]
+		if !ok {
+			return nil, nil, ErrJSONNotFound
+		}
+		if len(tokens) == 1 {
+			delete(c, t)
+			return c, x, nil
+		}
+		if c[t], removed, err = jsonRemove(x, tokens[1:]); err != nil {
+			return nil, nil, err
+		}
+		return c, removed, nil
+	case []interface{}:
+		i, err := jsonIndex(t, len(c)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(tokens) == 1 {
+			removed = c[i]
+			return append(c[:i], c[i+1:]...), removed, nil
+		}
+		if c[i], removed, err = jsonRemove(c[i], tokens[1:]); err != nil {
+			return nil, nil, err
+		}
+		return c, removed, nil
+	}
+	return nil, nil, ErrJSONNotFound
+}
+
+func copyJSON(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(c))
+		for k, x := range c {
+			m[k] = copyJSON(x)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(c))
+		for i, x := range c {
+			s[i] = copyJSON(x)
+		}
+		return s
+	}
+	return v
+}
+
+// jsonEqual reports whether decoded JSON values are equal, numbers are compared by value.
+func jsonEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, _, err1 := big.ParseFloat(string(x), 10, 256, big.ToNearestEven)
+		fy, _, err2 := big.ParseFloat(string(y), 10, 256, big.ToNearestEven)
+		return err1 == nil && err2 == nil && fx.Cmp(fy) == 0
+	}
+	return a == b
+}
+
+// JSONMergePatch applies the RFC 7396 JSON Merge Patch to doc and returns the patched document.
+// Object keys of the result are sorted.
+func JSONMergePatch(doc, patch []byte) ([]byte, error) {
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: bad merge patch: %w", err)
+	}
+	return AppendJSON(nil, mergeJSON(v, p))
+}
+
+func mergeJSON(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergeJSON(t[k], v)
+		}
+	}
+	return t
+}
+
+// JSONDiff returns a RFC 6902 JSON Patch that turns a into b.
+// Object members are compared by key and arrays are compared by index.
+func JSONDiff(a, b []byte) ([]byte, error) {
+	x, err := decodeJSON(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	ops := []jsonPatchOp{}
+	if err := diffJSON(&ops, nil, x, y); err != nil {
+		return nil, err
+	}
+	return AppendJSON(nil, ops)
+}
+
+func diffJSON(ops *[]jsonPatchOp, path []string, a, b interface{}) error {
+	at := func(key string) []string {
+		return append(path[:len(path):len(path)], key)
+	}
+	add := func(op string, p []string, v interface{}) error {
+		raw, err := AppendJSON(nil, v)
+		if err != nil {
+			return err
+		}
+		*ops = append(*ops, jsonPatchOp{Op: op, Path: formatJSONPointer(p), Value: raw})
+		return nil
+	}
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, k := range sortedKeys(x) {
+			if w, ok := y[k]; ok {
+				if err := diffJSON(ops, at(k), x[k], w); err != nil {
+					return err
+				}
+			} else {
+				*ops = append(*ops, jsonPatchOp{Op: "remove", Path: formatJSONPointer(at(k))})
+			}
+		}
+		for _, k := range sortedKeys(y) {
+			if _, ok := x[k]; !ok {
+				if err := add("add", at(k), y[k]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		n := len(x)
+		if len(y) < n {
+			n = len(y)
+		}
+		for i := 0; i < n; i++ {
+			if err := diffJSON(ops, at(strconv.Itoa(i)), x[i], y[i]); err != nil {
+				return err
+			}
+		}
+		for i := len(x) - 1; i >= n; i-- {
+			*ops = append(*ops, jsonPatchOp{Op: "remove", Path: formatJSONPointer(at(strconv.Itoa(i)))})
+		}
+		for i := n; i < len(y); i++ {
+			if err := add("add", at(strconv.Itoa(i)), y[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if jsonEqual(a, b) {
+		return nil
+	}
+	return add("replace", path, b)
+}
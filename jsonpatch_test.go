@@ -0,0 +1,97 @@
+package goutils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestJSONPatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":[1]}]`, `{"a":1,"b":[1]}`, ""},
+		{"add null", `{"a":1}`, `[{"op":"add","path":"/b","value":null}]`, `{"a":1,"b":null}`, ""},
+		{"add element", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2},{"op":"add","path":"/a/-","value":4}]`, `{"a":[1,2,3,4]}`, ""},
+		{"add root", `{"a":1}`, `[{"op":"add","path":"","value":true}]`, `true`, ""},
+		{"remove", `{"a":[1,2,3],"b":1}`, `[{"op":"remove","path":"/a/1"},{"op":"remove","path":"/b"}]`, `{"a":[1,3]}`, ""},
+		{"replace", `{"a":{"b":1}}`, `[{"op":"replace","path":"/a/b","value":"x"}]`, `{"a":{"b":"x"}}`, ""},
+		{"move", `{"a":{"b":1},"c":[]}`, `[{"op":"move","from":"/a/b","path":"/c/0"}]`, `{"a":{},"c":[1]}`, ""},
+		{"copy", `{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`, `{"a":{"b":[1]},"c":{"b":[1,2]}}`, ""},
+		{"test", `{"a":1.0,"b":[{"c":"d"}]}`, `[{"op":"test","path":"/a","value":1},{"op":"test","path":"/b","value":[{"c":"d"}]}]`, `{"a":1.0,"b":[{"c":"d"}]}`, ""},
+		{"test failed", `{"a":1}`, `[{"op":"test","path":"/a","value":"1"}]`, ``, `jsonpatch: op 0 (test "/a"): test failed`},
+		{"missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, ``, `value not found`},
+		{"remove missing", `{"a":[]}`, `[{"op":"remove","path":"/a/0"}]`, ``, `value not found`},
+		{"replace missing", `{}`, `[{"op":"replace","path":"/a","value":1}]`, ``, `value not found`},
+		{"move into child", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, ``, `cannot move`},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, ``, `missing value`},
+		{"unknown op", `{}`, `[{"op":"foo","path":"/a"}]`, ``, `unknown op "foo"`},
+		{"bad patch", `{}`, `{}`, ``, `bad patch`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(c.doc), []byte(c.patch))
+			if c.err != "" {
+				assert.NotNil(t, err)
+				assert.True(t, strings.Contains(err.Error(), c.err))
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, string(got), c.want)
+		})
+	}
+	_, err := JSONPatch([]byte(`{}`), []byte(`[{"op":"test","path":"","value":[]}]`))
+	assert.True(t, errors.Is(err, ErrJSONTestFailed))
+}
+
+func TestJSONMergePatch(t *testing.T) {
+	cases := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`["a"]`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		got, err := JSONMergePatch([]byte(c.doc), []byte(c.patch))
+		assert.Nil(t, err)
+		assert.Equal(t, string(got), c.want)
+	}
+}
+
+func TestJSONDiff(t *testing.T) {
+	cases := []struct {
+		a, b, want string
+	}{
+		{`{"a":1}`, `{"a":1.0}`, `[]`},
+		{`{"a":1,"b":2}`, `{"a":3,"c":null}`,
+			`[{"op":"replace","path":"/a","value":3},{"op":"remove","path":"/b"},{"op":"add","path":"/c","value":null}]`},
+		{`{"a":[1,2,3]}`, `{"a":[1,4]}`,
+			`[{"op":"replace","path":"/a/1","value":4},{"op":"remove","path":"/a/2"}]`},
+		{`[1]`, `[1,{"x/y":2}]`, `[{"op":"add","path":"/1","value":{"x/y":2}}]`},
+		{`{"a~b":{}}`, `{"a~b":[]}`, `[{"op":"replace","path":"/a~0b","value":[]}]`},
+		{`1`, `"1"`, `[{"op":"replace","path":"","value":"1"}]`},
+	}
+	for _, c := range cases {
+		got, err := JSONDiff([]byte(c.a), []byte(c.b))
+		assert.Nil(t, err)
+		assert.Equal(t, string(got), c.want)
+
+		patched, err := JSONPatch([]byte(c.a), got)
+		assert.Nil(t, err)
+		d, err := JSONDiff(patched, []byte(c.b))
+		assert.Nil(t, err)
+		assert.Equal(t, string(d), `[]`)
+	}
+}
@@ -0,0 +1,430 @@
+package goutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrJSONNotFound is returned if a JSON Pointer references a nonexistent value.
+var ErrJSONNotFound = errors.New("value not found")
+
+// JSONPointer returns the raw JSON value of doc referenced by the RFC 6901 JSON Pointer,
+// e.g. "/a/0/b". An empty pointer references the whole document.
+func JSONPointer(doc []byte, pointer string) (json.RawMessage, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	v, err = jsonPointerGet(v, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpointer: %q: %w", pointer, err)
+	}
+	return AppendJSON(nil, v)
+}
+
+// JSONPath returns raw JSON values of doc matched by the JSONPath expression.
+//
+// Supported syntax:
+//   $                        the root
+//   .name ['name'] ["name"]  object member
+//   [0] [-1]                 array element, negative index counts from the end
+//   [start:end:step]         array slice
+//   .* [*]                   all members or elements
+//   ..name ..*               recursive descent
+//   [a,b]                    union of selectors
+// Filter expressions are not supported. Object members are visited in key order.
+// No values matched is not an error.
+func JSONPath(doc []byte, path string) ([]json.RawMessage, error) {
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []interface{}{v}
+	for _, seg := range segs {
+		var next []interface{}
+		for _, n := range nodes {
+			if seg.descendant {
+				walkJSON(n, func(x interface{}) {
+					next = seg.selectors.apply(next, x)
+				})
+			} else {
+				next = seg.selectors.apply(next, n)
+			}
+		}
+		nodes = next
+	}
+	r := make([]json.RawMessage, 0, len(nodes))
+	for _, n := range nodes {
+		b, err := AppendJSON(nil, n)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, b)
+	}
+	return r, nil
+}
+
+// decodeJSON decodes a single JSON document with json.Number for numbers.
+func decodeJSON(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("json: invalid data after top-level value")
+	}
+	return v, nil
+}
+
+func parseJSONPointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("jsonpointer: %q: must start with /", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		if !strings.Contains(t, "~") {
+			continue
+		}
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1')) {
+				return nil, fmt.Errorf("jsonpointer: %q: bad escape", p)
+			}
+		}
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func formatJSONPointer(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.Replace(strings.Replace(t, "~", "~0", -1), "/", "~1", -1))
+	}
+	return b.String()
+}
+
+// jsonIndex parses an array index token, n is the max valid index.
+func jsonIndex(token string, n int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("bad array index %q", token)
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, fmt.Errorf("bad array index %q", token)
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > n {
+		return 0, ErrJSONNotFound
+	}
+	return i, nil
+}
+
+func jsonPointerGet(v interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			x, ok := c[t]
+			if !ok {
+				return nil, ErrJSONNotFound
+			}
+			v = x
+		case []interface{}:
+			i, err := jsonIndex(t, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			v = c[i]
+		default:
+			return nil, ErrJSONNotFound
+		}
+	}
+	return v, nil
+}
+
+// walkJSON calls f with v and all its descendants in document order.
+func walkJSON(v interface{}, f func(interface{})) {
+	f(v)
+	switch c := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(c) {
+			walkJSON(c[k], f)
+		}
+	case []interface{}:
+		for _, x := range c {
+			walkJSON(x, f)
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type jsonPathSegment struct {
+	descendant bool
+	selectors  jsonPathSelectors
+}
+
+type jsonPathSelector struct {
+	kind  byte // 'n' name, 'i' index, 's' slice, '*' wildcard
+	name  string
+	index [3]int
+	has   [3]bool
+}
+
+type jsonPathSelectors []jsonPathSelector
+
+// apply appends values of v matched by selectors to dst.
+func (ss jsonPathSelectors) apply(dst []interface{}, v interface{}) []interface{} {
+	for _, s := range ss {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			switch s.kind {
+			case 'n':
+				if x, ok := c[s.name]; ok {
+					dst = append(dst, x)
+				}
+			case '*':
+				for _, k := range sortedKeys(c) {
+					dst = append(dst, c[k])
+				}
+			}
+		case []interface{}:
+			switch s.kind {
+			case 'i':
+				i := s.index[0]
+				if i < 0 {
+					i += len(c)
+				}
+				if i >= 0 && i < len(c) {
+					dst = append(dst, c[i])
+				}
+			case 's':
+				dst = s.slice(dst, c)
+			case '*':
+				dst = append(dst, c...)
+			}
+		}
+	}
+	return dst
+}
+
+func (s *jsonPathSelector) slice(dst []interface{}, c []interface{}) []interface{} {
+	n := len(c)
+	step := 1
+	if s.has[2] {
+		step = s.index[2]
+	}
+	if step == 0 {
+		return dst
+	}
+	norm := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int) int {
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+	if step > 0 {
+		start, end := 0, n
+		if s.has[0] {
+			start = clamp(norm(s.index[0]), 0, n)
+		}
+		if s.has[1] {
+			end = clamp(norm(s.index[1]), 0, n)
+		}
+		for i := start; i < end; i += step {
+			dst = append(dst, c[i])
+		}
+		return dst
+	}
+	start, end := n-1, -1
+	if s.has[0] {
+		start = clamp(norm(s.index[0]), -1, n-1)
+	}
+	if s.has[1] {
+		end = clamp(norm(s.index[1]), -1, n-1)
+	}
+	for i := start; i > end; i += step {
+		dst = append(dst, c[i])
+	}
+	return dst
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	bad := func(msg string) error {
+		return fmt.Errorf("jsonpath: %q: %s", path, msg)
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, bad("must start with $")
+	}
+	var segs []jsonPathSegment
+	s := path[1:]
+	for s != "" {
+		var seg jsonPathSegment
+		switch {
+		case strings.HasPrefix(s, ".."):
+			seg.descendant = true
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				break
+			}
+			fallthrough
+		case s[0] == '.':
+			if !seg.descendant {
+				s = s[1:]
+			}
+			if strings.HasPrefix(s, "*") {
+				seg.selectors = jsonPathSelectors{{kind: '*'}}
+				s = s[1:]
+				segs = append(segs, seg)
+				continue
+			}
+			n := 0
+			for n < len(s) && s[n] != '.' && s[n] != '[' {
+				n++
+			}
+			if n == 0 {
+				return nil, bad("missing name")
+			}
+			seg.selectors = jsonPathSelectors{{kind: 'n', name: s[:n]}}
+			s = s[n:]
+			segs = append(segs, seg)
+			continue
+		case s[0] != '[':
+			return nil, bad("unexpected " + strconv.Quote(s[:1]))
+		}
+		var err error
+		seg.selectors, s, err = parseJSONPathBracket(s[1:])
+		if err != nil {
+			return nil, bad(err.Error())
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// parseJSONPathBracket parses selectors after [ and returns the rest after ].
+func parseJSONPathBracket(s string) (jsonPathSelectors, string, error) {
+	var ss jsonPathSelectors
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return nil, "", errors.New("unclosed [")
+		}
+		var sel jsonPathSelector
+		switch c := s[0]; {
+		case c == '\'' || c == '"':
+			name, n, err := parseJSONPathString(s)
+			if err != nil {
+				return nil, "", err
+			}
+			sel = jsonPathSelector{kind: 'n', name: name}
+			s = s[n:]
+		case c == '*':
+			sel.kind = '*'
+			s = s[1:]
+		case c == '?' || c == '(':
+			return nil, "", errors.New("filter expressions are not supported")
+		default:
+			n := strings.IndexAny(s, ",]")
+			if n < 0 {
+				return nil, "", errors.New("unclosed [")
+			}
+			var err error
+			sel, err = parseJSONPathIndex(strings.TrimSpace(s[:n]))
+			if err != nil {
+				return nil, "", err
+			}
+			s = s[n:]
+		}
+		ss = append(ss, sel)
+		s = strings.TrimLeft(s, " ")
+		switch {
+		case strings.HasPrefix(s, "]"):
+			return ss, s[1:], nil
+		case strings.HasPrefix(s, ","):
+			s = s[1:]
+		default:
+			return nil, "", errors.New("expect , or ]")
+		}
+	}
+}
+
+func parseJSONPathString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, errors.New("unclosed string")
+			}
+			i++
+			b.WriteByte(s[i])
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, errors.New("unclosed string")
+}
+
+func parseJSONPathIndex(s string) (jsonPathSelector, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return jsonPathSelector{}, fmt.Errorf("bad slice %q", s)
+	}
+	sel := jsonPathSelector{kind: 'i'}
+	if len(parts) > 1 {
+		sel.kind = 's'
+	}
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" && sel.kind == 's' {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return jsonPathSelector{}, fmt.Errorf("bad index %q", s)
+		}
+		sel.index[i] = n
+		sel.has[i] = true
+	}
+	return sel, nil
+}
@@ -0,0 +1,109 @@
+package goutils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+const jsonPointerDoc = `{
+	"store": {
+		"book": [
+			{"title": "a", "price": 8.95},
+			{"title": "b", "price": 12.99, "isbn": "0-553"},
+			{"title": "c", "price": 8.99}
+		],
+		"bicycle": {"price": 19.95}
+	},
+	"a/b": 1, "m~n": 2, "": 3
+}`
+
+func TestJSONPointer(t *testing.T) {
+	cases := []struct {
+		pointer string
+		want    string
+		err     error
+	}{
+		{"/store/book/1/title", `"b"`, nil},
+		{"/store/bicycle", `{"price":19.95}`, nil},
+		{"/a~1b", `1`, nil},
+		{"/m~0n", `2`, nil},
+		{"/", `3`, nil},
+		{"/store/book/3", ``, ErrJSONNotFound},
+		{"/store/book/-", ``, errors.New(`bad array index "-"`)},
+		{"/store/book/01", ``, errors.New(`bad array index "01"`)},
+		{"/nope", ``, ErrJSONNotFound},
+		{"/a~1b/c", ``, ErrJSONNotFound},
+		{"store", ``, errors.New("must start with /")},
+		{"/a~2", ``, errors.New("bad escape")},
+	}
+	for _, c := range cases {
+		t.Run(c.pointer, func(t *testing.T) {
+			got, err := JSONPointer([]byte(jsonPointerDoc), c.pointer)
+			assert.Equal(t, string(got), c.want)
+			switch {
+			case c.err == nil:
+				assert.Nil(t, err)
+			case c.err == ErrJSONNotFound:
+				assert.True(t, errors.Is(err, ErrJSONNotFound))
+			default:
+				assert.True(t, strings.Contains(err.Error(), c.err.Error()))
+			}
+		})
+	}
+	got, err := JSONPointer([]byte(jsonPointerDoc), "")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(got), `{"":3,"a/b":1,`))
+
+	_, err = JSONPointer([]byte(`{}{}`), "")
+	assert.NotNil(t, err)
+}
+
+func TestJSONPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+		err  string
+	}{
+		{"$", ``, ""},
+		{"$.store.book[0].title", `"a"`, ""},
+		{"$['store']['book'][-1].title", `"c"`, ""},
+		{`$.store.book[*].price`, `8.95 12.99 8.99`, ""},
+		{`$.store.book[0,2].title`, `"a" "c"`, ""},
+		{`$.store.book[1:].title`, `"b" "c"`, ""},
+		{`$.store.book[::-2].title`, `"c" "a"`, ""},
+		{`$.store.book[:-1].title`, `"a" "b"`, ""},
+		{`$..price`, `19.95 8.95 12.99 8.99`, ""},
+		{`$..book[?(@.isbn)]`, ``, "filter expressions are not supported"},
+		{`$..isbn`, `"0-553"`, ""},
+		{`$.store.*.price`, `19.95`, ""},
+		{`$["a/b", "m~n"]`, `1 2`, ""},
+		{`$.nope`, ``, ""},
+		{`$.store.book[x]`, ``, `bad index "x"`},
+		{`$.store.book[0`, ``, `unclosed [`},
+		{`store`, ``, `must start with $`},
+		{`$.`, ``, `missing name`},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			got, err := JSONPath([]byte(jsonPointerDoc), c.path)
+			if c.err != "" {
+				assert.NotNil(t, err)
+				assert.True(t, strings.Contains(err.Error(), c.err))
+				return
+			}
+			assert.Nil(t, err)
+			if c.path == "$" {
+				assert.Equal(t, len(got), 1)
+				return
+			}
+			var s []string
+			for _, r := range got {
+				s = append(s, string(r))
+			}
+			assert.Equal(t, strings.Join(s, " "), c.want)
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package goutils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultJSONRedactMask = "***"
+
+type jsonRedact struct {
+	patterns []string
+	mask     string
+	hashKey  []byte
+	hash     bool
+}
+
+func (o *jsonOption) redactor() *jsonRedact {
+	if o.redact == nil {
+		o.redact = &jsonRedact{mask: defaultJSONRedactMask}
+	}
+	return o.redact
+}
+
+// JSONRedact replaces values of struct fields tagged by `redact:"true"` and object members
+// whose key matches any of patterns.
+//
+// Patterns are matched by path.Match case-insensitively, e.g. "*password*", "authorization".
+// Patterns apply to keys of maps and JSON names of struct fields.
+// Values are replaced by "***" unless JSONRedactMask or JSONRedactHash is given.
+// The tag `redact:"mask"` or `redact:"hash"` chooses the replacement of a field.
+func JSONRedact(patterns ...string) JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		r := o.redactor()
+		for _, p := range patterns {
+			r.patterns = append(r.patterns, strings.ToLower(p))
+		}
+	})
+}
+
+// JSONRedactMask replaces redacted values by mask, it implies JSONRedact.
+func JSONRedactMask(mask string) JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		r := o.redactor()
+		r.mask = mask
+		r.hash = false
+	})
+}
+
+// JSONRedactHash replaces redacted values by "sha256:" and the first 16 hex digits of
+// HMAC-SHA256 of their JSON encoding, so equal values could be correlated without being exposed.
+// It implies JSONRedact.
+func JSONRedactHash(key []byte) JSONOption {
+	return jsonOptionFunc(func(o *jsonOption) {
+		r := o.redactor()
+		r.hashKey = key
+		r.hash = true
+	})
+}
+
+func (r *jsonRedact) match(key string) bool {
+	if len(r.patterns) == 0 {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, p := range r.patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// replace returns the replacement of v, mode is the value of redact tag.
+func (r *jsonRedact) replace(v reflect.Value, mode string) (interface{}, error) {
+	hash := r.hash
+	switch mode {
+	case "mask":
+		hash = false
+	case "hash":
+		hash = true
+	}
+	if !hash {
+		return r.mask, nil
+	}
+	var o interface{}
+	if v.IsValid() {
+		o = v.Interface()
+	}
+	b, err := AppendJSON(nil, o)
+	if err != nil {
+		return nil, err
+	}
+	m := hmac.New(sha256.New, r.hashKey)
+	m.Write(b)
+	return "sha256:" + hex.EncodeToString(m.Sum(nil)[:8]), nil
+}
+
+const jsonRedactMaxDepth = 1000
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// value returns a value encodes like v, but with sensitive values replaced.
+func (r *jsonRedact) value(v reflect.Value, depth int) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if depth > jsonRedactMaxDepth {
+		return nil, errors.New("json: unsupported value: encountered a cycle or too deep")
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return v.Interface(), nil
+	}
+	if v.CanAddr() && (reflect.PtrTo(t).Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return v.Addr().Interface(), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return r.value(v.Elem(), depth+1)
+	case reflect.Struct:
+		return r.structValue(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := jsonMapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			var x interface{}
+			if r.match(k) {
+				x, err = r.replace(iter.Value(), "")
+			} else {
+				x, err = r.value(iter.Value(), depth+1)
+			}
+			if err != nil {
+				return nil, err
+			}
+			m[k] = x
+		}
+		return m, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+		fallthrough
+	case reflect.Array:
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			x, err := r.value(v.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = x
+		}
+		return s, nil
+	}
+	return v.Interface(), nil
+}
+
+func (r *jsonRedact) structValue(v reflect.Value, depth int) (interface{}, error) {
+	var obj jsonObject
+	for _, f := range jsonFieldsOf(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyJSONValue(fv)) {
+			continue
+		}
+		var x interface{}
+		var err error
+		switch {
+		case f.redact != "" || r.match(f.name):
+			x, err = r.replace(fv, f.redact)
+		case f.quoted:
+			var b []byte
+			b, err = AppendJSON(nil, fv.Interface())
+			x = string(b)
+		default:
+			x, err = r.value(fv, depth+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj = append(obj, jsonMember{f.name, x})
+	}
+	if obj == nil {
+		obj = jsonObject{}
+	}
+	return obj, nil
+}
+
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func jsonMapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", errors.New("json: unsupported map key type " + k.Type().String())
+}
+
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// jsonMember is a member of jsonObject.
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// jsonObject is a JSON object keeps the order of members.
+type jsonObject []jsonMember
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	b := []byte{'{'}
+	for i, m := range o {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		var err error
+		if b, err = AppendJSON(b, m.key); err != nil {
+			return nil, err
+		}
+		b = append(b, ':')
+		if b, err = AppendJSON(b, m.value); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+type jsonField struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool
+	redact    string
+}
+
+// jsonFieldCache maps reflect.Type to []jsonField.
+var jsonFieldCache sync.Map
+
+// jsonFieldsOf returns fields of struct type t in the order of encoding/json.
+// Fields of embedded structs are promoted by the rules of encoding/json: a shallower field
+// hides deeper ones with the same name, a tagged field wins at the same depth,
+// otherwise fields with the same name at the same depth are all dropped.
+func jsonFieldsOf(t reflect.Type) []jsonField {
+	if f, ok := jsonFieldCache.Load(t); ok {
+		return f.([]jsonField)
+	}
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var all []jsonField
+	var current []embedded
+	next := []embedded{{typ: t}}
+	count := map[reflect.Type]int{}
+	nextCount := map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts := tag, ""
+				if n := strings.IndexByte(tag, ','); n >= 0 {
+					name, opts = tag[:n], tag[n:]
+				}
+				idx := append(e.index[:len(e.index):len(e.index)], i)
+				if sf.Anonymous && name == "" {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						if sf.PkgPath != "" {
+							continue
+						}
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						nextCount[ft]++
+						if nextCount[ft] == 1 {
+							next = append(next, embedded{ft, idx})
+						}
+						continue
+					}
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+				f := jsonField{
+					name:      name,
+					index:     idx,
+					tagged:    name != "",
+					omitEmpty: strings.Contains(opts+",", ",omitempty,"),
+					redact:    sf.Tag.Get("redact"),
+				}
+				if name == "" {
+					f.name = sf.Name
+				}
+				switch sf.Type.Kind() {
+				case reflect.Bool, reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+					reflect.Float32, reflect.Float64:
+					f.quoted = strings.Contains(opts+",", ",string,")
+				}
+				if f.redact == "false" {
+					f.redact = ""
+				}
+				all = append(all, f)
+				if count[e.typ] > 1 {
+					// The type is embedded more than once at the same depth,
+					// adds the field twice so that it's dropped.
+					all = append(all, f)
+				}
+			}
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		x, y := all[i], all[j]
+		if x.name != y.name {
+			return x.name < y.name
+		}
+		if len(x.index) != len(y.index) {
+			return len(x.index) < len(y.index)
+		}
+		if x.tagged != y.tagged {
+			return x.tagged
+		}
+		return lessIndex(x.index, y.index)
+	})
+	var fields []jsonField
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		if j-i == 1 || len(all[i].index) < len(all[i+1].index) || all[i].tagged != all[i+1].tagged {
+			fields = append(fields, all[i])
+		}
+		i = j
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return lessIndex(fields[i].index, fields[j].index)
+	})
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
+
+func lessIndex(x, y []int) bool {
+	for k, xk := range x {
+		if k >= len(y) {
+			return false
+		}
+		if xk != y[k] {
+			return xk < y[k]
+		}
+	}
+	return len(x) < len(y)
+}
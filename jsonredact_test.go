@@ -0,0 +1,149 @@
+package goutils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+type redactBase struct {
+	ID    int    `json:"id"`
+	Token string `json:"token" redact:"true"`
+}
+
+type redactUser struct {
+	redactBase
+	Name     string            `json:"name"`
+	Password string            `json:"password,omitempty" redact:"true"`
+	Secret   string            `json:"-"`
+	Card     string            `redact:"hash"`
+	Age      int               `json:"age,string"`
+	Headers  map[string]string `json:"headers"`
+	Friends  []*redactUser     `json:"friends,omitempty"`
+	Created  time.Time         `json:"created"`
+	Raw      []byte            `json:"raw"`
+	private  string
+}
+
+func TestJSONRedact(t *testing.T) {
+	u := &redactUser{
+		redactBase: redactBase{ID: 1, Token: "t0k3n"},
+		Name:       "<bob>",
+		Secret:     "s",
+		Card:       "4111",
+		Age:        18,
+		Headers:    map[string]string{"Authorization": "Bearer x", "X-Api-Key": "k", "Accept": "*/*"},
+		Friends:    []*redactUser{{Name: "amy", Password: "p"}},
+		Created:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Raw:        []byte("hi"),
+		private:    "p",
+	}
+	got := ToJSON(u, JSONRedact("authorization", "*key*"))
+	want := `{"id":1,"token":"***","name":"<bob>","Card":"sha256:9654c22a793f93bd","age":"18",` +
+		`"headers":{"Accept":"*/*","Authorization":"***","X-Api-Key":"***"},` +
+		`"friends":[{"id":0,"token":"***","name":"amy","password":"***","Card":"sha256:140b47411f10521c","age":"0",` +
+		`"headers":null,"created":"0001-01-01T00:00:00Z","raw":null}],` +
+		`"created":"2020-01-02T03:04:05Z","raw":"aGk="}`
+	assert.Equal(t, got, want)
+
+	got = ToJSON(u.Friends[0], JSONRedactMask("[hidden]"), JSONRedactHash([]byte("k")))
+	assert.True(t, strings.Contains(got, `"token":"sha256:`))
+	assert.True(t, strings.Contains(got, `"Card":"sha256:`))
+	again := ToJSON(u.Friends[0], JSONRedactHash([]byte("k")))
+	assert.Equal(t, got, again)
+	other := ToJSON(u.Friends[0], JSONRedactHash([]byte("other")))
+	assert.NotEqual(t, got, other)
+
+	got = ToJSON(redactBase{Token: "x"}, JSONRedactMask("[hidden]"), JSONSortKeys(), JSONIndent(" "))
+	assert.Equal(t, got, "{\n \"id\": 0,\n \"token\": \"[hidden]\"\n}")
+
+	assert.Equal(t, ToJSON(redactBase{Token: "x"}), `{"id":0,"token":"x"}`)
+}
+
+func TestJSONRedactValues(t *testing.T) {
+	type shadow struct {
+		redactBase
+		Token string `json:"token"`
+	}
+	cases := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, `null`},
+		{map[int]interface{}{2: []interface{}{map[string]int{"pwd": 1}}}, `{"2":[{"pwd":"***"}]}`},
+		{[2]redactBase{}, `[{"id":0,"token":"***"},{"id":0,"token":"***"}]`},
+		{shadow{Token: "visible"}, `{"id":0,"token":"visible"}`},
+		{struct{ *redactBase }{}, `{}`},
+		{"plain", `"plain"`},
+	}
+	for _, c := range cases {
+		got, err := MarshalString(c.v, JSONRedact("pwd"))
+		assert.Nil(t, err)
+		assert.Equal(t, got, c.want)
+	}
+
+	type node struct {
+		Next interface{}
+	}
+	n := &node{}
+	n.Next = n
+	_, err := MarshalString(n, JSONRedact())
+	assert.NotNil(t, err)
+}
+
+type redactNode struct {
+	*redactNode
+	Name string
+}
+
+type redactX1 struct{ X int }
+
+type redactX2 struct{ X int }
+
+type redactTagged struct {
+	X int `json:"X"`
+}
+
+type redactDup struct {
+	redactX1
+}
+
+func TestJSONRedactFields(t *testing.T) {
+	cases := []interface{}{
+		redactNode{Name: "a"},
+		redactNode{redactNode: &redactNode{Name: "b"}, Name: "a"},
+		struct {
+			redactX1
+			redactX2
+			Y int
+		}{redactX1{1}, redactX2{2}, 3},
+		struct {
+			redactX1
+			redactTagged
+		}{redactX1{1}, redactTagged{2}},
+		struct {
+			redactDup
+			redactX2
+			X2 redactX1
+		}{redactDup{redactX1{1}}, redactX2{2}, redactX1{3}},
+		struct {
+			redactDup
+			redactX1
+		}{redactDup{redactX1{1}}, redactX1{2}},
+		struct {
+			A struct{ redactX1 }
+			B int `json:"X"`
+			redactX2
+		}{B: 2},
+	}
+	for _, c := range cases {
+		want, err := json.Marshal(c)
+		assert.Nil(t, err)
+		got, err := MarshalString(c, JSONRedact("pw"))
+		assert.Nil(t, err)
+		assert.Equal(t, got, string(want))
+	}
+}
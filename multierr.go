@@ -2,6 +2,8 @@ package goutils
 
 import (
 	"errors"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -18,69 +20,227 @@ import (
 //    }
 //
 //  MultiErr all method is goroutine-safe(lock-guarded).
+//  Ids are listed in the order they are first set, unless MultiErrSorted is given.
+//  FanOut runs the loop above with a concurrency limit, timeouts and panic recovery.
 type MultiErr struct {
-	mu   sync.Mutex
-	errs map[string]error
+	mu      sync.Mutex
+	index   map[string]int
+	entries []multiErrEntry
+	nerrs   int
+	dropped int
+	limit   int
+	sorted  bool
 }
 
-// Set sets an id with an error.
+type multiErrEntry struct {
+	id  string
+	err error
+}
+
+// MultiErrOption configures MultiErr.
+type MultiErrOption interface {
+	apply(*MultiErr)
+}
+
+type multiErrOptionFunc func(*MultiErr)
+
+func (f multiErrOptionFunc) apply(m *MultiErr) {
+	f(m)
+}
+
+// MultiErrLimit limits the number of stored errors, errors of new ids beyond the limit are dropped
+// and counted by Dropped. Zero means no limit.
+func MultiErrLimit(n int) MultiErrOption {
+	return multiErrOptionFunc(func(m *MultiErr) {
+		m.limit = n
+	})
+}
+
+// MultiErrSorted lists ids in lexical order instead of insertion order.
+func MultiErrSorted() MultiErrOption {
+	return multiErrOptionFunc(func(m *MultiErr) {
+		m.sorted = true
+	})
+}
+
+// NewMultiErr returns a MultiErr with options, a zero MultiErr is ready to use without options.
+func NewMultiErr(opts ...MultiErrOption) *MultiErr {
+	m := new(MultiErr)
+	for _, a := range opts {
+		a.apply(m)
+	}
+	return m
+}
+
+// Set sets an id with an error. An error beyond MultiErrLimit is dropped and counted by Dropped,
+// the id is kept as is if it's set before, e.g. a success stays a success.
 func (m *MultiErr) Set(id string, err error) {
 	m.mu.Lock()
-	if m.errs == nil {
-		m.errs = map[string]error{}
+	defer m.mu.Unlock()
+	i, ok := m.index[id]
+	old := ok && m.entries[i].err != nil
+	switch {
+	case err != nil && !old:
+		if m.limit > 0 && m.nerrs >= m.limit {
+			m.dropped++
+			return
+		}
+		m.nerrs++
+	case err == nil && old:
+		m.nerrs--
 	}
-	m.errs[id] = err
-	m.mu.Unlock()
+	if ok {
+		m.entries[i].err = err
+		return
+	}
+	if m.index == nil {
+		m.index = map[string]int{}
+	}
+	m.index[id] = len(m.entries)
+	m.entries = append(m.entries, multiErrEntry{id, err})
 }
 
 // Get gets the id's error. If id not absent, it returns a nil.
 func (m *MultiErr) Get(id string) (err error) {
-	m.mu.Lock()
-	if m.errs != nil {
-		err = m.errs[id]
-	}
-	m.mu.Unlock()
+	err, _ = m.GetE(id)
 	return
 }
 
 // GetE gets the id's error and a bool represent if id exists.
+// Ids of dropped errors do not exist unless they are set before, see Set.
 func (m *MultiErr) GetE(id string) (err error, ok bool) { // nolint: revive
 	m.mu.Lock()
-	if m.errs != nil {
-		err, ok = m.errs[id]
+	i, ok := m.index[id]
+	if ok {
+		err = m.entries[i].err
 	}
 	m.mu.Unlock()
 	return
 }
 
-// All returns true if all id's error is nil.
-func (m *MultiErr) All() (all bool) {
+// All returns true if all id's error is nil and no error is dropped.
+func (m *MultiErr) All() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.errs != nil {
-		for _, v := range m.errs {
-			if v != nil {
-				return
-			}
-		}
-	}
-	all = true
-	return
+	return m.nerrs == 0 && m.dropped == 0
 }
 
-// Error returns an error. It returns nil if All returns true.
+// Dropped returns the number of errors dropped by MultiErrLimit.
+func (m *MultiErr) Dropped() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dropped
+}
+
+// Error returns a *MultiError of failed ids. It returns nil if All returns true.
 func (m *MultiErr) Error() error {
-	if m.All() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.multiError(); e != nil {
+		return e
+	}
+	return nil
+}
+
+// multiError returns failed ids or nil, it must be called with lock held.
+func (m *MultiErr) multiError() *MultiError {
+	if m.nerrs == 0 && m.dropped == 0 {
 		return nil
 	}
-	return errors.New(m.String())
+	e := &MultiError{Dropped: m.dropped}
+	for _, x := range m.ordered() {
+		if x.err != nil {
+			e.IDs = append(e.IDs, x.id)
+			e.Errs = append(e.Errs, x.err)
+		}
+	}
+	return e
+}
+
+// MultiError is the error returned by MultiErr.Error, Errs[i] is the error of IDs[i].
+type MultiError struct {
+	IDs     []string
+	Errs    []error
+	Dropped int // number of errors dropped by MultiErrLimit
+}
+
+func (e *MultiError) Error() string {
+	var sb strings.Builder
+	for i, err := range e.Errs {
+		if i > 0 {
+			sb.WriteByte(';')
+		}
+		sb.WriteString(e.IDs[i])
+		sb.WriteByte(':')
+		sb.WriteString(err.Error())
+	}
+	if e.Dropped > 0 {
+		if len(e.Errs) > 0 {
+			sb.WriteByte(';')
+		}
+		sb.WriteString("and ")
+		sb.WriteString(strconv.Itoa(e.Dropped))
+		sb.WriteString(" more errors")
+	}
+	return sb.String()
+}
+
+// Unwrap returns the errors.
+func (e *MultiError) Unwrap() []error {
+	return e.Errs
+}
+
+// Is reports whether any error in e matches target.
+func (e *MultiError) Is(target error) bool {
+	_, ok := e.Find(target)
+	return ok
+}
+
+// As finds the first error in e that matches target.
+func (e *MultiError) As(target interface{}) bool {
+	_, ok := e.FindAs(target)
+	return ok
+}
+
+// Find returns the id of the first error that matches target by errors.Is.
+func (e *MultiError) Find(target error) (id string, ok bool) {
+	for i, err := range e.Errs {
+		if errors.Is(err, target) {
+			return e.IDs[i], true
+		}
+	}
+	return "", false
+}
+
+// FindAs returns the id of the first error that matches target by errors.As,
+// and sets target to that error.
+func (e *MultiError) FindAs(target interface{}) (id string, ok bool) {
+	for i, err := range e.Errs {
+		if errors.As(err, target) {
+			return e.IDs[i], true
+		}
+	}
+	return "", false
+}
+
+// ordered returns entries in listing order, it must be called with lock held.
+func (m *MultiErr) ordered() []multiErrEntry {
+	if !m.sorted {
+		return m.entries
+	}
+	entries := make([]multiErrEntry, len(m.entries))
+	copy(entries, m.entries)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].id < entries[j].id
+	})
+	return entries
 }
 
 // Range loops errors. It stops loop if `f` returns false.
 func (m *MultiErr) Range(f func(id string, err error) bool) {
 	m.mu.Lock()
-	for k, v := range m.errs {
-		if !f(k, v) {
+	for _, e := range m.ordered() {
+		if !f(e.id, e.err) {
 			break
 		}
 	}
@@ -91,25 +251,13 @@ func (m *MultiErr) Range(f func(id string, err error) bool) {
 func (m *MultiErr) String() string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.errs == nil {
+	if m.entries == nil && m.dropped == 0 {
 		return "success"
 	}
-
-	var sb strings.Builder
-	var notfisrt bool
-	for id, err := range m.errs {
-		if err != nil {
-			if notfisrt {
-				sb.WriteByte(';')
-			} else {
-				notfisrt = true
-			}
-			sb.WriteString(id)
-			sb.WriteByte(':')
-			sb.WriteString(err.Error())
-		}
+	if e := m.multiError(); e != nil {
+		return e.Error()
 	}
-	return sb.String()
+	return ""
 }
 
 // Successes gets successful id list.
@@ -117,22 +265,22 @@ func (m *MultiErr) Successes() (ids []string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for k, v := range m.errs {
-		if v == nil {
-			ids = append(ids, k)
+	for _, e := range m.ordered() {
+		if e.err == nil {
+			ids = append(ids, e.id)
 		}
 	}
 	return ids
 }
 
-// Fails gets failed id list.
+// Fails gets failed id list, ids of dropped errors are not included.
 func (m *MultiErr) Fails() (ids []string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for k, v := range m.errs {
-		if v != nil {
-			ids = append(ids, k)
+	for _, e := range m.ordered() {
+		if e.err != nil {
+			ids = append(ids, e.id)
 		}
 	}
 	return ids
@@ -1,8 +1,13 @@
 package goutils
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"testing"
+
+	"github.com/hanke0/goutils/assert"
 )
 
 func TestMultiErr(t *testing.T) {
@@ -68,3 +73,118 @@ func TestMultiErr(t *testing.T) {
 		t.Fatal("not stop loop, nn=", nn)
 	}
 }
+
+func TestMultiErrOrder(t *testing.T) {
+	m := NewMultiErr()
+	for _, id := range []string{"c", "a", "d", "b"} {
+		if id == "a" || id == "b" {
+			m.Set(id, nil)
+		} else {
+			m.Set(id, errors.New("fail "+id))
+		}
+	}
+	m.Set("c", errors.New("fail c again"))
+	assert.Equal(t, m.String(), "c:fail c again;d:fail d")
+	assert.Equal(t, m.Fails(), []string{"c", "d"})
+	assert.Equal(t, m.Successes(), []string{"a", "b"})
+
+	m.Set("d", nil)
+	assert.Equal(t, m.Successes(), []string{"a", "d", "b"})
+
+	s := NewMultiErr(MultiErrSorted())
+	for _, id := range []string{"c", "a", "b"} {
+		s.Set(id, errors.New(id))
+	}
+	assert.Equal(t, s.String(), "a:a;b:b;c:c")
+	var ids []string
+	s.Range(func(id string, err error) bool {
+		ids = append(ids, id)
+		return true
+	})
+	assert.Equal(t, ids, []string{"a", "b", "c"})
+}
+
+func TestMultiErrLimit(t *testing.T) {
+	m := NewMultiErr(MultiErrLimit(2))
+	for i := 0; i < 10; i++ {
+		m.Set(strconv.Itoa(i), errors.New("fail"))
+		m.Set("ok"+strconv.Itoa(i), nil)
+	}
+	assert.Equal(t, m.Dropped(), 8)
+	assert.Equal(t, m.Fails(), []string{"0", "1"})
+	assert.Equal(t, len(m.Successes()), 10)
+	assert.Equal(t, m.Error().Error(), "0:fail;1:fail;and 8 more errors")
+	_, ok := m.GetE("5")
+	assert.False(t, ok)
+
+	m.Set("1", nil)
+	m.Set("x", errors.New("fail x"))
+	assert.Equal(t, m.Fails(), []string{"0", "x"})
+
+	d := NewMultiErr(MultiErrLimit(1))
+	d.Set("a", errors.New("a"))
+	d.Set("a", nil)
+	d.Set("b", errors.New("b"))
+	d.Set("c", errors.New("c"))
+	assert.False(t, d.All())
+	assert.Equal(t, d.String(), "b:b;and 1 more errors")
+
+	e := NewMultiErr(MultiErrLimit(1))
+	for _, id := range []string{"a", "b", "c"} {
+		e.Set(id, nil)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		e.Set(id, errors.New(id))
+	}
+	assert.Equal(t, e.Dropped(), 2)
+	assert.Equal(t, e.Fails(), []string{"a"})
+	assert.Equal(t, e.Successes(), []string{"b", "c"})
+	err, ok := e.GetE("c")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.False(t, e.All())
+	e.Set("a", errors.New("a2"))
+	e.Set("d", nil)
+	assert.Equal(t, e.String(), "a:a2;and 2 more errors")
+	assert.Equal(t, e.Successes(), []string{"b", "c", "d"})
+	assert.Nil(t, e.Get("d"))
+}
+
+type idErr struct {
+	code int
+}
+
+func (e *idErr) Error() string {
+	return "code " + strconv.Itoa(e.code)
+}
+
+func TestMultiErrUnwrap(t *testing.T) {
+	var m MultiErr
+	m.Set("a", nil)
+	m.Set("b", fmt.Errorf("call b: %w", context.DeadlineExceeded))
+	m.Set("c", &idErr{3})
+	err := m.Error()
+	assert.Equal(t, err.Error(), "b:call b: context deadline exceeded;c:code 3")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.Is(err, context.Canceled))
+
+	var ie *idErr
+	assert.True(t, errors.As(err, &ie))
+	assert.Equal(t, ie.code, 3)
+
+	var me *MultiError
+	assert.True(t, errors.As(err, &me))
+	id, ok := me.Find(context.DeadlineExceeded)
+	assert.True(t, ok)
+	assert.Equal(t, id, "b")
+	_, ok = me.Find(context.Canceled)
+	assert.False(t, ok)
+	ie = nil
+	id, ok = me.FindAs(&ie)
+	assert.True(t, ok)
+	assert.Equal(t, id, "c")
+	assert.Equal(t, len(me.Unwrap()), 2)
+
+	wrapped := fmt.Errorf("batch: %w", err)
+	assert.True(t, errors.Is(wrapped, context.DeadlineExceeded))
+}
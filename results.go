@@ -0,0 +1,219 @@
+package goutils
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Result is the record of an id in Results.
+type Result struct {
+	ID       string
+	Value    interface{}
+	Err      error
+	Attempts int
+	Start    time.Time // start time of the first attempt
+	End      time.Time // end time of the last attempt, zero if not done
+}
+
+// Done returns true if the result is finished.
+func (r Result) Done() bool {
+	return !r.End.IsZero()
+}
+
+// Duration returns the time from Start to End, or zero if not done.
+func (r Result) Duration() time.Duration {
+	if !r.Done() {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+// Results represent a bucket of results of ids, it likes MultiErr but records values and metadata.
+// Usually it should be used as follow:
+//    var r Results
+//    for _, id := range []string{"1", "2"} {
+//      id := id
+//      go func1() {
+//        r.Start(id)
+//        v, err := somefunc1(id)
+//        r.Done(id, v, err)
+//      }
+//    }
+//
+//  Results all method is goroutine-safe(lock-guarded).
+//  Ids are listed in the order they are first recorded.
+type Results struct {
+	mu      sync.Mutex
+	index   map[string]int
+	entries []Result
+}
+
+// entry returns the result of id, creating it if absent. It must be called with lock held.
+func (r *Results) entry(id string) *Result {
+	if i, ok := r.index[id]; ok {
+		return &r.entries[i]
+	}
+	if r.index == nil {
+		r.index = map[string]int{}
+	}
+	r.index[id] = len(r.entries)
+	r.entries = append(r.entries, Result{ID: id})
+	return &r.entries[len(r.entries)-1]
+}
+
+// Start starts an attempt of id. It increases the attempt count and clears the last end time,
+// start time is only set by the first attempt.
+func (r *Results) Start(id string) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.entry(id)
+	e.Attempts++
+	if e.Start.IsZero() {
+		e.Start = now
+	}
+	e.End = time.Time{}
+}
+
+// Done finishes the current attempt of id with value and err.
+// An id done without Start is counted as one attempt started now.
+func (r *Results) Done(id string, value interface{}, err error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.entry(id)
+	if e.Attempts == 0 {
+		e.Attempts = 1
+	}
+	if e.Start.IsZero() {
+		e.Start = now
+	}
+	e.Value = value
+	e.Err = err
+	e.End = now
+}
+
+// Set replaces the result of res.ID.
+func (r *Results) Set(res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.entry(res.ID) = res
+}
+
+// Get gets the result of id and a bool represent if id exists.
+func (r *Results) Get(id string) (res Result, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.index[id]
+	if ok {
+		res = r.entries[i]
+	}
+	return
+}
+
+// Len returns the number of ids.
+func (r *Results) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// All returns a copy of all results.
+func (r *Results) All() []Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]Result, len(r.entries))
+	copy(res, r.entries)
+	return res
+}
+
+// Range loops results. It stops loop if `f` returns false.
+func (r *Results) Range(f func(res Result) bool) {
+	r.mu.Lock()
+	for _, e := range r.entries {
+		if !f(e) {
+			break
+		}
+	}
+	r.mu.Unlock()
+}
+
+// MultiErr returns errors of done ids as a MultiErr.
+func (r *Results) MultiErr(opts ...MultiErrOption) *MultiErr {
+	m := NewMultiErr(opts...)
+	r.Range(func(res Result) bool {
+		if res.Done() {
+			m.Set(res.ID, res.Err)
+		}
+		return true
+	})
+	return m
+}
+
+// SuccessRatio returns the ratio of successful ids in done ids, it returns 0 if no id is done.
+func (r *Results) SuccessRatio() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var done, ok int
+	for _, e := range r.entries {
+		if e.Done() {
+			done++
+			if e.Err == nil {
+				ok++
+			}
+		}
+	}
+	if done == 0 {
+		return 0
+	}
+	return float64(ok) / float64(done)
+}
+
+// Slowest returns at most n done results with the longest durations, slowest first.
+func (r *Results) Slowest(n int) []Result {
+	r.mu.Lock()
+	var res []Result
+	for _, e := range r.entries {
+		if e.Done() {
+			res = append(res, e)
+		}
+	}
+	r.mu.Unlock()
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Duration() > res[j].Duration()
+	})
+	if n >= 0 && n < len(res) {
+		res = res[:n]
+	}
+	return res
+}
+
+// ErrorsByMessage groups failed ids by error message, ids not done are skipped.
+func (r *Results) ErrorsByMessage() map[string][]string {
+	return r.groupErrors(func(err error) string {
+		return err.Error()
+	})
+}
+
+// ErrorsByType groups failed ids by error type name, such as "*errors.errorString",
+// ids not done are skipped.
+func (r *Results) ErrorsByType() map[string][]string {
+	return r.groupErrors(func(err error) string {
+		return fmt.Sprintf("%T", err)
+	})
+}
+
+func (r *Results) groupErrors(key func(error) string) map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	groups := map[string][]string{}
+	for _, e := range r.entries {
+		if e.Done() && e.Err != nil {
+			k := key(e.Err)
+			groups[k] = append(groups[k], e.ID)
+		}
+	}
+	return groups
+}
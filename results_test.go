@@ -0,0 +1,114 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestResults(t *testing.T) {
+	var r Results
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			r.Start(id)
+			switch i {
+			case 1:
+				r.Done(id, nil, errors.New("timeout"))
+			case 2:
+				r.Done(id, nil, context.Canceled)
+			case 3:
+				r.Done(id, nil, errors.New("timeout"))
+			default:
+				r.Done(id, i*i, nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, r.Len(), 10)
+	res, ok := r.Get("4")
+	assert.True(t, ok)
+	assert.Equal(t, res.Value, 16)
+	assert.Equal(t, res.Attempts, 1)
+	assert.True(t, res.Done())
+	_, ok = r.Get("10")
+	assert.False(t, ok)
+
+	assert.Equal(t, r.SuccessRatio(), 0.7)
+	byMsg := r.ErrorsByMessage()
+	assert.Equal(t, len(byMsg), 2)
+	assert.Equal(t, len(byMsg["timeout"]), 2)
+	assert.Equal(t, len(byMsg["context canceled"]), 1)
+	byType := r.ErrorsByType()
+	assert.Equal(t, len(byType["*errors.errorString"]), 3)
+
+	m := r.MultiErr(MultiErrSorted())
+	assert.Equal(t, m.Fails(), []string{"1", "2", "3"})
+	assert.True(t, errors.Is(m.Error(), context.Canceled))
+}
+
+func TestResultsAttempts(t *testing.T) {
+	var r Results
+	r.Start("a")
+	first, _ := r.Get("a")
+	assert.False(t, first.Done())
+	assert.Equal(t, first.Duration(), time.Duration(0))
+	r.Done("a", nil, errors.New("fail"))
+	r.Start("a")
+	res, _ := r.Get("a")
+	assert.False(t, res.Done())
+	assert.Equal(t, res.Start, first.Start)
+	assert.Equal(t, len(r.ErrorsByMessage()), 0)
+	assert.Equal(t, len(r.ErrorsByType()), 0)
+	r.Done("a", "ok", nil)
+	res, _ = r.Get("a")
+	assert.Equal(t, res.Attempts, 2)
+	assert.Nil(t, res.Err)
+	assert.Equal(t, res.Value, "ok")
+
+	r.Done("b", 1, nil)
+	res, _ = r.Get("b")
+	assert.Equal(t, res.Attempts, 1)
+	assert.Equal(t, res.Start, res.End)
+
+	r.Start("c")
+	assert.Equal(t, r.SuccessRatio(), 1.0)
+	assert.Equal(t, len(r.MultiErr().Successes()), 2)
+	assert.Equal(t, len(r.All()), 3)
+}
+
+func TestResultsSlowest(t *testing.T) {
+	var r Results
+	now := time.Now()
+	for i, d := range []int{3, 1, 5, 2} {
+		r.Set(Result{
+			ID:    strconv.Itoa(i),
+			Start: now,
+			End:   now.Add(time.Duration(d) * time.Second),
+		})
+	}
+	r.Start("pending")
+	ids := func(res []Result) (ids []string) {
+		for _, x := range res {
+			ids = append(ids, x.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, ids(r.Slowest(2)), []string{"2", "0"})
+	assert.Equal(t, ids(r.Slowest(-1)), []string{"2", "0", "3", "1"})
+	assert.Equal(t, len(r.Slowest(0)), 0)
+	assert.Equal(t, r.SuccessRatio(), 1.0)
+
+	var empty Results
+	assert.Equal(t, empty.SuccessRatio(), 0.0)
+	assert.Equal(t, len(empty.Slowest(3)), 0)
+}
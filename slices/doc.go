@@ -0,0 +1,5 @@
+// Package slices provides generic functions for slices of any type.
+//
+// The module declares go 1.13, the generic functions are built with Go 1.21 or later only,
+// which is the first version allows build constraints to upgrade the language version of a file.
+package slices // import "github.com/hanke0/goutils/slices"
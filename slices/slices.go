@@ -0,0 +1,164 @@
+//go:build go1.21
+// +build go1.21
+
+package slices
+
+// Contains reports whether v is present in s.
+func Contains[T comparable](s []T, v T) bool {
+	return Index(s, v) >= 0
+}
+
+// Index returns the index of the first occurrence of v in s, or -1 if not present.
+func Index[T comparable](s []T, v T) int {
+	for i := range s {
+		if s[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// Count counts the number of v in s.
+func Count[T comparable](s []T, v T) (num int) {
+	for i := range s {
+		if s[i] == v {
+			num++
+		}
+	}
+	return
+}
+
+// Filter returns a new slice of elements that keep returns true.
+func Filter[T any](s []T, keep func(T) bool) []T {
+	var r []T
+	for _, v := range s {
+		if keep(v) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// Map returns a new slice of f applied to each element.
+func Map[T, U any](s []T, f func(T) U) []U {
+	if s == nil {
+		return nil
+	}
+	r := make([]U, len(s))
+	for i, v := range s {
+		r[i] = f(v)
+	}
+	return r
+}
+
+// Reduce folds s from left to right by f, starts from init.
+func Reduce[T, A any](s []T, init A, f func(acc A, v T) A) A {
+	acc := init
+	for _, v := range s {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Uniq returns a new slice without duplicated elements, the first occurrence is kept.
+func Uniq[T comparable](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	seen := make(map[T]struct{}, len(s))
+	r := make([]T, 0, len(s))
+	for _, v := range s {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// GroupBy groups elements by key, elements in each group keep their order.
+func GroupBy[T any, K comparable](s []T, key func(T) K) map[K][]T {
+	r := map[K][]T{}
+	for _, v := range s {
+		k := key(v)
+		r[k] = append(r[k], v)
+	}
+	return r
+}
+
+// Chunk splits s into slices of size elements, the last one may be shorter.
+// The chunks share the underlying array with s. It panics if size is not positive.
+func Chunk[T any](s []T, size int) [][]T {
+	if size <= 0 {
+		panic("slices: chunk size must be positive")
+	}
+	r := make([][]T, 0, (len(s)+size-1)/size)
+	for len(s) > size {
+		r = append(r, s[:size:size])
+		s = s[size:]
+	}
+	if len(s) > 0 {
+		r = append(r, s)
+	}
+	return r
+}
+
+// Partition splits s into elements that pred returns true and the others.
+func Partition[T any](s []T, pred func(T) bool) (matched, others []T) {
+	for _, v := range s {
+		if pred(v) {
+			matched = append(matched, v)
+		} else {
+			others = append(others, v)
+		}
+	}
+	return
+}
+
+// Pair is a pair of values.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip pairs elements of a and b by index, the result is as long as the shorter one.
+func Zip[A, B any](a []A, b []B) []Pair[A, B] {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	r := make([]Pair[A, B], n)
+	for i := range r {
+		r[i] = Pair[A, B]{a[i], b[i]}
+	}
+	return r
+}
+
+// Difference returns unique elements of a that are not in b, in order of a.
+func Difference[T comparable](a, b []T) []T {
+	return filterSet(a, b, false)
+}
+
+// Intersect returns unique elements of a that are also in b, in order of a.
+func Intersect[T comparable](a, b []T) []T {
+	return filterSet(a, b, true)
+}
+
+func filterSet[T comparable](a, b []T, in bool) []T {
+	set := make(map[T]struct{}, len(b))
+	for _, v := range b {
+		set[v] = struct{}{}
+	}
+	seen := make(map[T]struct{}, len(a))
+	var r []T
+	for _, v := range a {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		if _, ok := set[v]; ok == in {
+			r = append(r, v)
+		}
+	}
+	return r
+}
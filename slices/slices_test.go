@@ -0,0 +1,65 @@
+//go:build go1.21
+// +build go1.21
+
+package slices_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+	"github.com/hanke0/goutils/slices"
+)
+
+type id struct {
+	n int
+}
+
+func TestSearch(t *testing.T) {
+	ids := []id{{1}, {2}, {1}}
+	assert.True(t, slices.Contains(ids, id{2}))
+	assert.False(t, slices.Contains(ids, id{3}))
+	assert.Equal(t, slices.Index(ids, id{1}), 0)
+	assert.Equal(t, slices.Index(ids, id{3}), -1)
+	assert.Equal(t, slices.Count(ids, id{1}), 2)
+	assert.Equal(t, slices.Count([]int(nil), 1), 0)
+}
+
+func TestTransform(t *testing.T) {
+	s := []int{1, 2, 3, 4, 5}
+	even := func(v int) bool { return v%2 == 0 }
+	assert.Equal(t, slices.Filter(s, even), []int{2, 4})
+	assert.Equal(t, slices.Map(s, strconv.Itoa), []string{"1", "2", "3", "4", "5"})
+	assert.Equal(t, slices.Map([]int(nil), strconv.Itoa), []string(nil))
+	assert.Equal(t, slices.Reduce(s, "", func(acc string, v int) string { return acc + strconv.Itoa(v) }), "12345")
+	assert.Equal(t, slices.Uniq([]int{3, 1, 3, 2, 1}), []int{3, 1, 2})
+	assert.Equal(t, slices.GroupBy(s, even), map[bool][]int{true: {2, 4}, false: {1, 3, 5}})
+
+	matched, others := slices.Partition(s, even)
+	assert.Equal(t, matched, []int{2, 4})
+	assert.Equal(t, others, []int{1, 3, 5})
+}
+
+func TestChunk(t *testing.T) {
+	s := []int{1, 2, 3, 4, 5}
+	assert.Equal(t, slices.Chunk(s, 2), [][]int{{1, 2}, {3, 4}, {5}})
+	assert.Equal(t, slices.Chunk(s, 5), [][]int{{1, 2, 3, 4, 5}})
+	assert.Equal(t, slices.Chunk([]int{}, 2), [][]int{})
+
+	c := slices.Chunk(s, 2)
+	c[0] = append(c[0], 10)
+	assert.Equal(t, s[2], 3)
+
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	slices.Chunk(s, 0)
+}
+
+func TestSet(t *testing.T) {
+	a := []string{"a", "b", "c", "a"}
+	b := []string{"c", "d"}
+	assert.Equal(t, slices.Difference(a, b), []string{"a", "b"})
+	assert.Equal(t, slices.Intersect(a, b), []string{"c"})
+	assert.Equal(t, slices.Zip(a, b), []slices.Pair[string, string]{{"a", "c"}, {"b", "d"}})
+}
@@ -0,0 +1,23 @@
+//go:build go1.21
+// +build go1.21
+
+package strings
+
+import "github.com/hanke0/goutils/slices"
+
+// Contains checks if a string exists in the string slice.
+// See slices.Contains for other element types.
+func Contains(bucket []string, want string) bool {
+	return slices.Contains(bucket, want)
+}
+
+// Index get the first index of string slice that value equals to want, or -1
+// if not any value equals to `want`.
+func Index(bucket []string, want string) int {
+	return slices.Index(bucket, want)
+}
+
+// Count counts the number of values that equals to want in the string slice.
+func Count(bucket []string, want string) int {
+	return slices.Count(bucket, want)
+}
@@ -0,0 +1,30 @@
+//go:build !go1.21
+// +build !go1.21
+
+package strings
+
+// Contains checks if a string exists in the string slice.
+func Contains(bucket []string, want string) bool {
+	return Index(bucket, want) != -1
+}
+
+// Index get the first index of string slice that value equals to want, or -1
+// if not any value equals to `want`.
+func Index(bucket []string, want string) int {
+	for i, v := range bucket {
+		if v == want {
+			return i
+		}
+	}
+	return -1
+}
+
+// Count counts the number of values that equals to want in the string slice.
+func Count(bucket []string, want string) (num int) {
+	for _, v := range bucket {
+		if v == want {
+			num++
+		}
+	}
+	return
+}
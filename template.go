@@ -0,0 +1,119 @@
+package goutils
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// Template is a pre-parsed Expand template, it's useful for expanding the
+// same template many times. A Template is safe for concurrent use.
+type Template struct {
+	nodes []node
+	opt   expandOption
+	errs  []error // syntax errors
+}
+
+// Variable is a variable referenced by a Template.
+type Variable struct {
+	Name string
+	// Op is the expansion operator, e.g. ":-" for ${var:-default},
+	// "len" for ${#var} and empty for ${var}.
+	Op string
+	// Default is the source text of word for ${var-word}, ${var:-word},
+	// ${var=word} and ${var:=word}.
+	Default string
+}
+
+// Compile parses s as an Expand template.
+// Like Expand, it returns a Template outputs bad syntax and invalid utf8 bytes as is
+// with the syntax error.
+func Compile(s string, opts ...ExpandOption) (*Template, error) {
+	t := &Template{}
+	for _, a := range opts {
+		a.apply(&t.opt)
+	}
+	if len(s) == 0 {
+		return t, nil
+	}
+	p := parser{s: s, opt: &t.opt}
+	t.nodes = p.parse("")
+	t.errs = p.errs
+	return t, t.opt.error(t.errs)
+}
+
+// Execute expands the template by mapping, see Expand for details.
+// Syntax errors are reported by Compile only.
+func (t *Template) Execute(mapping func(varName string, default_ string) string) (string, error) {
+	out, errs := t.execute(context.Background(), mappingLookup(mapping))
+	return out, t.opt.error(errs)
+}
+
+// ExecuteLookup expands the template by lookup, see ExpandLookup for details.
+func (t *Template) ExecuteLookup(lookup func(varName string) (string, bool)) (string, error) {
+	out, errs := t.execute(context.Background(), plainLookup(lookup))
+	return out, t.opt.error(errs)
+}
+
+// ExecuteContext expands the template by a mapping could fail, see ExpandContext for details.
+func (t *Template) ExecuteContext(ctx context.Context,
+	mapping func(ctx context.Context, varName string, default_ string) (string, error)) (string, error) {
+	out, errs := t.execute(ctx, contextLookup(ctx, mapping))
+	return out, t.opt.error(errs)
+}
+
+// ExecuteTo likes Execute but writes the result to w.
+func (t *Template) ExecuteTo(w io.Writer, mapping func(varName string, default_ string) string) error {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	e := evaluator{ctx: context.Background(), lookup: mappingLookup(mapping), opt: &t.opt}
+	e.eval(bw, t.nodes)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return t.opt.error(e.errs)
+}
+
+func (t *Template) execute(ctx context.Context, lookup lookupFunc) (string, []error) {
+	if len(t.nodes) == 0 {
+		return "", nil
+	}
+	if len(t.nodes) == 1 && t.nodes[0].v == nil {
+		return t.nodes[0].lit, nil
+	}
+	e := evaluator{ctx: ctx, lookup: lookup, opt: &t.opt}
+	var sb strings.Builder
+	e.eval(&sb, t.nodes)
+	return sb.String(), e.errs
+}
+
+// Variables lists variables referenced by the template in order of appearance,
+// including the nested ones. Duplicated references are listed once.
+func (t *Template) Variables() []Variable {
+	var vars []Variable
+	seen := map[Variable]bool{}
+	var walk func(nodes []node)
+	walk = func(nodes []node) {
+		for _, n := range nodes {
+			if n.v == nil {
+				continue
+			}
+			v := Variable{Name: n.v.name, Op: n.v.op}
+			switch v.Op {
+			case "-", ":-", "=", ":=":
+				v.Default = n.v.raw
+			}
+			if !seen[v] {
+				seen[v] = true
+				vars = append(vars, v)
+			}
+			walk(n.v.word)
+			walk(n.v.repl)
+		}
+	}
+	walk(t.nodes)
+	return vars
+}
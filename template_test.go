@@ -0,0 +1,72 @@
+package goutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hanke0/goutils/assert"
+)
+
+func TestTemplate(t *testing.T) {
+	tpl, err := Compile("${HOST:=${DEFAULT_HOST}}:${PORT:-80}/${PATH#/}")
+	assert.Nil(t, err)
+	assert.Equal(t, tpl.Variables(), []Variable{
+		{Name: "HOST", Op: ":=", Default: "${DEFAULT_HOST}"},
+		{Name: "DEFAULT_HOST"},
+		{Name: "PORT", Op: ":-", Default: "80"},
+		{Name: "PATH", Op: "#"},
+	})
+
+	for i := 0; i < 3; i++ {
+		env := map[string]string{"DEFAULT_HOST": "localhost", "PATH": fmt.Sprintf("/%d", i)}
+		mapping := func(name, def string) string {
+			if v, ok := env[name]; ok {
+				return v
+			}
+			return def
+		}
+		want := fmt.Sprintf("localhost:80/%d", i)
+		got, err := tpl.Execute(mapping)
+		assert.Nil(t, err)
+		assert.Equal(t, got, want)
+
+		var buf bytes.Buffer
+		assert.Nil(t, tpl.ExecuteTo(&buf, mapping))
+		assert.Equal(t, buf.String(), want)
+	}
+
+	got, err := tpl.ExecuteLookup(func(name string) (string, bool) {
+		if name == "HOST" {
+			return "", true
+		}
+		return "", false
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, got, ":80/")
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write fails")
+}
+
+func TestTemplateError(t *testing.T) {
+	tpl, err := Compile("${a:?}${}")
+	assert.NotNil(t, err)
+	got, err := tpl.Execute(func(string, string) string { return "" })
+	assert.Equal(t, got, "${}")
+	var ue *UnsetError
+	assert.True(t, errors.As(err, &ue))
+
+	err = tpl.ExecuteTo(errWriter{}, func(string, string) string { return "1" })
+	assert.Equal(t, err.Error(), "write fails")
+
+	tpl, err = Compile("\xe4\xb8${a}")
+	assert.NotNil(t, err)
+	got, err = tpl.Execute(func(string, string) string { return "1" })
+	assert.Equal(t, got, "\xe4\xb81")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,132 @@
+package goutils
+
+import "unicode/utf8"
+
+// TruncateOption configures Truncate.
+type TruncateOption interface {
+	apply(*truncateOption)
+}
+
+type truncateUnit int
+
+const (
+	truncateRunes truncateUnit = iota
+	truncateGraphemes
+	truncateWidth
+)
+
+type truncateOption struct {
+	unit   truncateUnit
+	middle bool
+}
+
+type truncateOptionFunc func(*truncateOption)
+
+func (f truncateOptionFunc) apply(o *truncateOption) {
+	f(o)
+}
+
+// TruncateGraphemes counts user-perceived characters instead of runes,
+// so emoji sequences and combining marks are never split. See Graphemes.
+func TruncateGraphemes() TruncateOption {
+	return truncateOptionFunc(func(o *truncateOption) {
+		o.unit = truncateGraphemes
+	})
+}
+
+// TruncateWidth counts terminal columns instead of runes, see StringWidth.
+// Grapheme clusters are never split.
+func TruncateWidth() TruncateOption {
+	return truncateOptionFunc(func(o *truncateOption) {
+		o.unit = truncateWidth
+	})
+}
+
+// TruncateMiddle keeps both the head and the tail of the string and puts suffix between them,
+// e.g. "abc…xyz".
+func TruncateMiddle() TruncateOption {
+	return truncateOptionFunc(func(o *truncateOption) {
+		o.middle = true
+	})
+}
+
+// Truncate returns s if it's not longer than max, otherwise cuts s and adds suffix,
+// the result including suffix is never longer than max.
+//
+// Length is counted in runes by default, TruncateGraphemes and TruncateWidth change it.
+// If suffix itself is longer than max, the truncated suffix is returned.
+// If max is lower than 0, then return s.
+//
+// Unlike ShortcutUTF8, max includes the suffix.
+func Truncate(s string, max int, suffix string, opts ...TruncateOption) string {
+	if max < 0 {
+		return s
+	}
+	var o truncateOption
+	for _, a := range opts {
+		a.apply(&o)
+	}
+	units := o.split(s)
+	var total int
+	for _, u := range units {
+		total += u.size
+	}
+	if total <= max {
+		return s
+	}
+	var suffixSize int
+	for _, u := range o.split(suffix) {
+		suffixSize += u.size
+	}
+	budget := max - suffixSize
+	if budget < 0 {
+		return Truncate(suffix, max, "", opts...)
+	}
+	if !o.middle {
+		i, _ := takeUnits(units, budget)
+		return s[:units[i].start] + suffix
+	}
+
+	i, used := takeUnits(units, (budget+1)/2)
+	j := len(units) - 1
+	for left := budget - used; j > i && units[j-1].size <= left; j-- {
+		left -= units[j-1].size
+	}
+	return s[:units[i].start] + suffix + s[units[j].start:]
+}
+
+type textUnit struct {
+	start int
+	size  int
+}
+
+// split splits s into units ends with a sentinel unit at len(s).
+func (o *truncateOption) split(s string) []textUnit {
+	var r []textUnit
+	for i := 0; i < len(s); {
+		var n, size int
+		switch o.unit {
+		case truncateRunes:
+			_, n = utf8.DecodeRuneInString(s[i:])
+			size = 1
+		case truncateGraphemes:
+			n = nextGrapheme(s[i:])
+			size = 1
+		case truncateWidth:
+			n = nextGrapheme(s[i:])
+			size = graphemeWidth(s[i : i+n])
+		}
+		r = append(r, textUnit{start: i, size: size})
+		i += n
+	}
+	return append(r, textUnit{start: len(s)})
+}
+
+// takeUnits returns the number of leading units fit in budget and their size.
+func takeUnits(units []textUnit, budget int) (n, size int) {
+	for n < len(units)-1 && size+units[n].size <= budget {
+		size += units[n].size
+		n++
+	}
+	return
+}
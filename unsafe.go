@@ -0,0 +1,14 @@
+//go:build go1.21
+// +build go1.21
+
+package goutils
+
+import "unsafe"
+
+func unsafeStringToBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+func unsafeBytesToString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
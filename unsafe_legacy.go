@@ -0,0 +1,22 @@
+//go:build !go1.21
+// +build !go1.21
+
+package goutils
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+func unsafeStringToBytes(s string) (b []byte) {
+	sh := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	bh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	bh.Data = sh.Data
+	bh.Len = sh.Len
+	bh.Cap = sh.Len
+	return b
+}
+
+func unsafeBytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}